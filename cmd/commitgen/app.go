@@ -5,70 +5,317 @@ import (
 	"CommitGen/internal/config"
 	"CommitGen/internal/git"
 	"context"
+	"errors"
+	"fmt"
 	"log"
-	"time"
+	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 type application struct {
 	logger   *log.Logger
+	cfg      *config.Config
 	provider ai.LLMProvider
+
+	// ctx spans the whole generation and cancel aborts the in-flight provider requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// updates carries progress messages from the running generation to the TUI.
+	updates chan tea.Msg
+
+	// existingCommitMessage is the message found in the commit message file, if any.
+	existingCommitMessage string
+
+	partial   string
+	progress  *summaryProgressMsg
+	response  *ai.GenerateResponse
+	err       error
+	cancelled bool
+
+	// cursor is the index of the highlighted candidate and accepted the message the user chose.
+	cursor   int
+	accepted string
 }
 
-func initialApplication(logger *log.Logger, providerName, apiKey, model, commitType *string, temperature *float64, maxTokens *int) application {
+func initialApplication(
+	ctx context.Context,
+	logger *log.Logger,
+	providerName, apiKey, model, commitType *string,
+	temperature *float64,
+	maxTokens, candidates *int,
+	noCache *bool,
+	existingCommitMessage string,
+) application {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		logger.Fatalf("Error loading configuration: %v", err)
 	}
-	cfg.OverrideFromFlags(commitType, providerName, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, providerName, apiKey, model, temperature, maxTokens, candidates, noCache)
+	// Fill in global defaults for a provider that only exists because of the flags.
+	cfg.SetupLocalProviderOverrides()
 
-	provider, err := ai.GetProvider(cfg)
+	app, err := newApplication(ctx, logger, cfg, existingCommitMessage)
+	// The warnings include the providers that were skipped while initializing the provider chain.
+	for _, warning := range cfg.Warnings {
+		logger.Printf("Warning: %s\n", warning)
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
 	if err != nil {
 		logger.Fatalf("Error initializing AI provider: %v", err)
 	}
+	return app
+}
+
+/*
+newApplication creates the TUI model for an already loaded configuration. Cancelling ctx, e.g.
+on SIGINT, aborts the in-flight provider requests just like cancelling from the TUI.
+*/
+func newApplication(ctx context.Context, logger *log.Logger, cfg *config.Config, existingCommitMessage string) (application, error) {
+	provider, err := ai.GetProvider(cfg)
+	if err != nil {
+		return application{}, err
+	}
 
-	return application{
+	ctx, cancel := context.WithCancel(ctx)
+	app := application{
 		logger:   logger,
+		cfg:      cfg,
 		provider: provider,
+		ctx:      ctx,
+		cancel:   cancel,
+		updates:  make(chan tea.Msg),
+
+		existingCommitMessage: existingCommitMessage,
 	}
+	return app, nil
 }
 
 func (a application) Init() tea.Cmd {
-	return nil
+	return tea.Batch(a.generateCommitMessageCmd, a.waitForUpdateCmd)
 }
 
 func (a application) View() string {
-	return ""
+	switch {
+	case a.cancelled:
+		return fmt.Sprintf("Generation cancelled.\n\n%s\n\nPress q to quit.\n", a.partial)
+	case a.err != nil:
+		return fmt.Sprintf("Error: %v\n\nPress q to quit.\n", a.err)
+	case a.response != nil:
+		return a.candidatesView()
+	case a.partial != "":
+		return fmt.Sprintf("%s\n\nPress esc to cancel.\n", a.partial)
+	case a.progress != nil && a.progress.done < a.progress.total:
+		return fmt.Sprintf(
+			"Summarizing large staged changes... (%d/%d file groups)\n\nPress esc to cancel.\n",
+			a.progress.done, a.progress.total,
+		)
+	}
+	return "Generating commit message...\n\nPress esc to cancel.\n"
 }
 
 func (a application) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			if a.generating() {
+				a.cancel()
+				a.cancelled = true
+				return a, nil
+			}
+			return a, tea.Quit
+		case "q", "ctrl+c":
+			if a.generating() {
+				a.logger.Printf("Generation cancelled by the user\n")
+			}
+			a.cancel()
+			return a, tea.Quit
+		case "up", "k":
+			if a.cursor > 0 {
+				a.cursor--
+			}
+		case "down", "j":
+			if a.response != nil && a.cursor < len(a.candidates())-1 {
+				a.cursor++
+			}
+		case "enter":
+			if a.response != nil {
+				a.accepted = a.candidates()[a.cursor]
+				return a, tea.Quit
+			}
+		}
+
+	case commitChunkMsg:
+		if a.generating() {
+			a.partial = msg.partial
+		}
+		return a, a.waitForUpdateCmd
+
+	case summaryProgressMsg:
+		// Summaries report their progress concurrently, so a late count must not move it back.
+		if a.generating() && (a.progress == nil || msg.done > a.progress.done) {
+			a.progress = &msg
+		}
+		return a, a.waitForUpdateCmd
 
 	case commitMessageMsg:
+		for _, attempt := range msg.resp.Fallbacks {
+			a.logger.Printf("Provider %s failed, fell back to the next provider: %v\n", attempt.Provider, attempt.Err)
+		}
+		a.logger.Printf("Commit message generated by %s (%s)\n", msg.resp.Provider, msg.resp.Model)
+		if msg.resp.Thoughts != "" {
+			a.logger.Printf("Model thoughts:\n%s\n", msg.resp.Thoughts)
+		}
+		a.response = &msg.resp
 
 	case errorMsg:
-		a.logger.Printf("Encounterd error: %v\n", msg.err)
+		switch {
+		case errors.Is(msg.err, context.Canceled) && a.cancelled:
+			a.logger.Printf("Generation cancelled by the user\n")
+			return a, nil
+		case errors.Is(msg.err, context.Canceled):
+			a.logger.Printf("Generation cancelled: %v\n", msg.err)
+		case errors.Is(msg.err, context.DeadlineExceeded):
+			a.logger.Printf("Generation timed out: %v\n", msg.err)
+		default:
+			a.logger.Printf("Encounterd error: %v\n", msg.err)
+		}
+		a.err = msg.err
 	}
 	return a, nil
 }
 
-type commitMessageMsg struct{ msg string }
+// candidates returns the generated messages the user can choose from.
+func (a application) candidates() []string {
+	if len(a.response.Candidates) > 1 {
+		return a.response.Candidates
+	}
+	return []string{a.response.Message}
+}
+
+// candidatesView renders the generated messages with the highlighted one marked by a cursor.
+func (a application) candidatesView() string {
+	candidates := a.candidates()
+
+	var view strings.Builder
+	if len(candidates) > 1 {
+		view.WriteString("Choose a commit message:\n\n")
+	}
+	for i, candidate := range candidates {
+		prefix := "  "
+		if len(candidates) > 1 && i == a.cursor {
+			prefix = "> "
+		}
+		// Indent continuation lines so multi-line messages stay visually grouped.
+		view.WriteString(prefix + strings.ReplaceAll(candidate, "\n", "\n  ") + "\n\n")
+	}
+
+	view.WriteString(describeResponse(*a.response) + "\n\n")
+	if a.response.FinishReason == ai.FinishReasonMaxTokens {
+		view.WriteString("Warning: the message was cut off by max_tokens and may be incomplete.\n\n")
+	}
+	if len(candidates) > 1 {
+		view.WriteString("Use up/down to move, enter to accept, q to quit.\n")
+	} else {
+		view.WriteString("Press enter to accept, q to quit.\n")
+	}
+	return view.String()
+}
+
+// generating reports whether the provider is still working on the commit message.
+func (a application) generating() bool {
+	return a.response == nil && a.err == nil && !a.cancelled
+}
+
+/*
+describeResponse returns a one-line summary of which provider and model produced the message,
+mentioning the providers that failed before it when a fallback happened.
+*/
+func describeResponse(resp ai.GenerateResponse) string {
+	description := fmt.Sprintf("Generated by %s (%s)", resp.Provider, resp.Model)
+	if resp.Cached {
+		description += " (cached)"
+	}
+	if len(resp.Fallbacks) == 0 {
+		return description
+	}
+
+	failed := make([]string, len(resp.Fallbacks))
+	for i, attempt := range resp.Fallbacks {
+		failed[i] = fmt.Sprintf("%s: %s", attempt.Provider, ai.ClassifyError(attempt.Err))
+	}
+	return fmt.Sprintf("%s after %s failed", description, strings.Join(failed, ", "))
+}
+
+type commitChunkMsg struct{ partial string }
+type summaryProgressMsg struct{ done, total int }
+type commitMessageMsg struct{ resp ai.GenerateResponse }
 type errorMsg struct{ err error }
 
+/*
+generateCommitMessageCmd asks the provider for a commit message. Staged changes that are too
+large are summarized first. Progress and the text generated so far are sent to a.updates,
+which is closed once generation is over.
+*/
 func (a application) generateCommitMessageCmd() tea.Msg {
+	defer close(a.updates)
+
 	stagedDiff, err := git.GetStagedDiff()
 	if err != nil {
 		return errorMsg{err}
 	}
+	data := ai.NewPromptData(a.cfg, stagedDiff, a.existingCommitMessage)
+	if data.RepoContext, err = git.GetRepoContext(a.cfg.Prompt.RecentCommits); err != nil {
+		// The context only improves the message, so generate without it.
+		a.logger.Printf("Could not collect the repository context: %v\n", err)
+	}
+	if data.Style, err = styleProfile(a.cfg.Prompt.Style); err != nil {
+		a.logger.Printf("Could not load the style profile: %v\n", err)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	summarizer := ai.NewSummarizer(a.cfg, a.provider)
+	if summarizer.NeedsSummary(stagedDiff) {
+		a.logger.Printf("Staged diff is too large, summarizing it first\n")
+		summary, _, err := summarizer.Summarize(a.ctx, stagedDiff, func(done, total int) {
+			a.sendUpdate(summaryProgressMsg{done, total})
+		})
+		if err != nil {
+			return errorMsg{err}
+		}
+		data.StagedDiff = summary
+		data.Summarized = true
+	}
 
-	commitMsg, err := a.provider.Generate(ctx, stagedDiff, "")
+	// Every provider request is bounded by the configured timeout, so a.ctx only carries cancellation.
+	resp, err := a.provider.Generate(a.ctx, ai.GenerateRequest{
+		PromptData: data,
+		Options:    ai.GenerateOptions{Candidates: a.cfg.AI.Candidates},
+		Stream: func(partial string) {
+			a.sendUpdate(commitChunkMsg{partial})
+		},
+	})
 	if err != nil {
 		return errorMsg{err}
 	}
-	return commitMessageMsg{commitMsg}
+	return commitMessageMsg{resp}
+}
+
+// sendUpdate passes msg to the TUI unless the generation was cancelled in the meantime.
+func (a application) sendUpdate(msg tea.Msg) {
+	select {
+	case a.updates <- msg:
+	case <-a.ctx.Done():
+	}
+}
+
+// waitForUpdateCmd waits for the next progress message, returning nil once generation is over.
+func (a application) waitForUpdateCmd() tea.Msg {
+	msg, ok := <-a.updates
+	if !ok {
+		return nil
+	}
+	return msg
 }
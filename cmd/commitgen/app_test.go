@@ -0,0 +1,167 @@
+package main
+
+import (
+	"CommitGen/internal/config"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+/*
+setupStagedRepo initializes a Git repository in a temporary directory with main.go staged,
+and makes it the working directory for the rest of the test.
+*/
+func setupStagedRepo(t *testing.T) {
+	t.Helper()
+	repoPath := t.TempDir()
+	t.Chdir(repoPath)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("could not write main.go: %v", err)
+	}
+	for _, args := range [][]string{{"git", "init"}, {"git", "add", "main.go"}} {
+		if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("command %q failed: %v\nOutput: %s", strings.Join(args, " "), err, string(output))
+		}
+	}
+}
+
+// setupMockConfig returns a default config using the mock provider without the cache or the usage ledger.
+func setupMockConfig() *config.Config {
+	cfg := config.NewDefaultConfig()
+	cfg.AI.DefaultProvider = config.Mock
+	cfg.AI.Cache.Enabled = false
+	cfg.AI.Usage.Enabled = false
+	cfg.Prompt.Style.Enabled = false
+	cfg.SetupLocalProviderOverrides()
+	return cfg
+}
+
+/*
+runApplication plays the part of the Bubble Tea runtime: it runs the generation, feeds every
+progress message and the result to the model, then sends keys and returns the final model.
+*/
+func runApplication(t *testing.T, cfg *config.Config, existingCommitMessage string, keys ...tea.KeyMsg) application {
+	t.Helper()
+	app, err := newApplication(t.Context(), log.New(io.Discard, "", 0), cfg, existingCommitMessage)
+	if err != nil {
+		t.Fatalf("newApplication failed: %v", err)
+	}
+
+	result := make(chan tea.Msg)
+	go func() { result <- app.generateCommitMessageCmd() }()
+
+	var model tea.Model = app
+	for msg := app.waitForUpdateCmd(); msg != nil; msg = app.waitForUpdateCmd() {
+		model, _ = model.Update(msg)
+	}
+	model, _ = model.Update(<-result)
+
+	for _, key := range keys {
+		model, _ = model.Update(key)
+	}
+	return model.(application)
+}
+
+func TestApplication_AcceptMessage(t *testing.T) {
+	setupStagedRepo(t)
+
+	app := runApplication(t, setupMockConfig(), "", tea.KeyMsg{Type: tea.KeyEnter})
+	if app.err != nil {
+		t.Fatalf("generation failed: %v", app.err)
+	}
+
+	expected := "refactor: Update 1 file\n\n- Update main.go"
+	if app.accepted != expected {
+		t.Errorf("expected the generated message %q to be accepted, got %q", expected, app.accepted)
+	}
+	if app.partial != expected {
+		t.Errorf("expected the message to have been streamed, got %q", app.partial)
+	}
+	if !strings.Contains(app.View(), "Generated by mock (mock)") {
+		t.Errorf("expected the view to name the provider, got:\n%s", app.View())
+	}
+}
+
+func TestApplication_ChooseCandidate(t *testing.T) {
+	setupStagedRepo(t)
+	cfg := setupMockConfig()
+	cfg.AI.Candidates = 2
+	mockCfg := cfg.AI.Providers[config.Mock]
+	mockCfg.Responses = []string{"feat: First", "fix: Second"}
+	cfg.AI.Providers[config.Mock] = mockCfg
+
+	app := runApplication(t, cfg, "", tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter})
+	if app.err != nil {
+		t.Fatalf("generation failed: %v", app.err)
+	}
+
+	// Candidates are generated in parallel, so their order is not fixed.
+	candidates := slices.Sorted(slices.Values(app.candidates()))
+	if !slices.Equal(candidates, []string{"feat: First", "fix: Second"}) {
+		t.Fatalf("expected both responses as candidates, got %q", app.candidates())
+	}
+	if app.accepted != app.candidates()[1] {
+		t.Errorf("expected the second candidate %q to be accepted, got %q", app.candidates()[1], app.accepted)
+	}
+}
+
+func TestCommitMessageFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keys     []tea.KeyMsg
+		expected string
+	}{
+		{
+			name:     "Accept",
+			keys:     []tea.KeyMsg{{Type: tea.KeyEnter}},
+			expected: "refactor: Update 1 file\n\n- Update main.go\n# Please enter the commit message for your changes.\n# On branch main",
+		},
+		{
+			name:     "Quit",
+			keys:     []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("q")}},
+			expected: "\n# Please enter the commit message for your changes.\n# On branch main\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupStagedRepo(t)
+			// The file as git passes it to the prepare-commit-msg hook.
+			commitMsgFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			content := "\n# Please enter the commit message for your changes.\n# On branch main\n"
+			if err := os.WriteFile(commitMsgFile, []byte(content), 0644); err != nil {
+				t.Fatalf("could not write the commit message file: %v", err)
+			}
+
+			logger := log.New(io.Discard, "", 0)
+			message, comments := readCommitMessageFile(logger, commitMsgFile)
+			app := runApplication(t, setupMockConfig(), message, tc.keys...)
+			if app.err != nil {
+				t.Fatalf("generation failed: %v", app.err)
+			}
+
+			var stdout strings.Builder
+			if err := writeCommitMessage(&stdout, commitMsgFile, app.accepted, comments); err != nil {
+				t.Fatalf("writeCommitMessage failed: %v", err)
+			}
+			written, err := os.ReadFile(commitMsgFile)
+			if err != nil {
+				t.Fatalf("could not read the commit message file: %v", err)
+			}
+			if string(written) != tc.expected {
+				t.Errorf("expected the commit message file to hold %q, got %q", tc.expected, string(written))
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("expected nothing to be printed, got %q", stdout.String())
+			}
+		})
+	}
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"CommitGen/internal/config"
+	"CommitGen/internal/git"
+	"context"
+	"errors"
 	"flag"
+	"os/signal"
 	"path/filepath"
-	"runtime"
+	"syscall"
 
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -15,41 +22,17 @@ import (
 const appName = "commitgen"
 
 /*
-getAppLoggerPath returns the path used for logging
-
-It first checks for the XDG_STATE_HOME environment variable. If the variable
-doesn't exist, it falls back based on the operating system.
-
-The logger path falls back to on the of the following:
+getAppLoggerPath returns the path used for logging, inside the state directory
+returned by config.StateDir:
 
 - Linux/macOS Fallback: ~/.local/state/commitgen/commitgen.log
 
 - Windows Fallback:     %LOCALAPPDATA%\commitgen\commitgen.log
 */
 func getAppLoggerPath() (string, error) {
-	var stateDir string
-
-	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
-		stateDir = xdgState
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("could not find user home directory: %w", err)
-		}
-
-		if runtime.GOOS == "windows" {
-			stateDir = os.Getenv("LOCALAPPDATA")
-			if stateDir == "" {
-				stateDir = filepath.Join(homeDir, "AppData", "Local")
-			}
-		} else {
-			stateDir = filepath.Join(homeDir, ".local", "state")
-		}
-	}
-
-	logDir := filepath.Join(stateDir, appName)
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return "", fmt.Errorf("could not create log directory at %s: %w", logDir, err)
+	logDir, err := config.StateDir()
+	if err != nil {
+		return "", err
 	}
 
 	logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", appName))
@@ -75,13 +58,15 @@ func main() {
 
 	logger := log.New(logFile, "", log.Ldate|log.Ltime|log.Lshortfile)
 
-	// commitMsgFile := flag.String("commit-msg-file", "", "Path to the commit message file (used by git hook)")
-	provider := flag.String("provider", "", "AI provider to use (e.g., gemini, openai)")
+	commitMsgFile := flag.String("commit-msg-file", "", "Path to the commit message file (used by git hook)")
+	provider := flag.String("provider", "", "AI provider to use (e.g., gemini, openai, anthropic, openai_compatible, ollama, exec)")
 	apiKey := flag.String("api-key", "", "API key for the AI provider")
 	model := flag.String("model", "", "AI model to use")
 	commitType := flag.String("commit-type", "", "Type of commit (e.g., feat, fix, test)")
 	temperature := flag.Float64("temperature", -1.0, "Temperature for the AI model")
 	maxTokens := flag.Int("max-tokens", -1, "Maximum number of tokens for the AI model")
+	candidates := flag.Int("candidates", -1, "Number of alternative commit messages to generate")
+	noCache := flag.Bool("no-cache", false, "Skip the response cache and always call the AI provider")
 	flag.Parse()
 
 	// After parsing flags, check for subcommands
@@ -96,57 +81,86 @@ func main() {
 		case "generate-config":
 			GenerateConfigFunc()
 			return
+		case "cache":
+			CacheFunc(flag.Args()[1:])
+			return
+		case "usage":
+			UsageFunc(flag.Args()[1:])
+			return
+		case "learn-style":
+			LearnStyleFunc(flag.Args()[1:])
+			return
+		case "config":
+			ConfigFunc(flag.Args()[1:])
+			return
 		case "help":
-			fmt.Println("Available commands: install-hook, uninstall-hook, generate-config")
+			fmt.Println("Available commands: install-hook, uninstall-hook, generate-config, cache clear, usage, learn-style, config show")
 			return
 		}
 	}
 
-	app := initialApplication(logger, provider, apiKey, model, commitType, temperature, maxTokens)
-	p := tea.NewProgram(app, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	var existingCommitMessage string
+	var existingCommitMessageComments string
+	if *commitMsgFile != "" {
+		existingCommitMessage, existingCommitMessageComments = readCommitMessageFile(logger, *commitMsgFile)
+	}
+
+	// SIGINT and SIGTERM cancel the in-flight provider requests instead of abandoning them.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app := initialApplication(ctx, logger, provider, apiKey, model, commitType, temperature, maxTokens, candidates, noCache, existingCommitMessage)
+	// The program is killed once ctx is cancelled, so a signal never leaves it on an error screen.
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithContext(ctx))
+	finalModel, err := p.Run()
+	if errors.Is(err, tea.ErrInterrupted) || ctx.Err() != nil {
+		logger.Printf("Generation interrupted\n")
+		os.Exit(130)
+	}
+	if err != nil {
 		fmt.Printf("Failed to start TUI application: %v", err)
 		os.Exit(1)
 	}
 
-	// stagedDiff, err := git.GetStagedDiff()
-	// if err != nil {
-	// 	logger.Fatalf("Error getting stagedDiff: %v", err)
-	// }
-	//
-	// var existingCommitMessage string
-	// var existingCommitMessageComments string
-	// if commitMsgFile != nil && *commitMsgFile != "" {
-	// 	content, err := os.ReadFile(*commitMsgFile)
-	// 	if err != nil {
-	// 		logger.Printf("Warning: Could not read existing commit message file %s: %v", *commitMsgFile, err)
-	// 	} else {
-	// 		nonCommented, commented := git.ParseCommitMessage(string(content))
-	// 		existingCommitMessage = nonCommented
-	// 		existingCommitMessageComments = commented
-	// 	}
-	// }
-	//
-	// ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	// defer cancel()
-	//
-	// commitMessage, err := geminiProvider.Generate(ctx, stagedDiff, existingCommitMessage)
-	// if err != nil {
-	// 	logger.Fatalf("Error generating commit message: %v", err)
-	// }
-	//
-	// finalCommitMessage := commitMessage
-	// if existingCommitMessageComments != "" {
-	// 	finalCommitMessage = fmt.Sprintf("%s\n%s", commitMessage, existingCommitMessageComments)
-	// }
-	//
-	// if commitMsgFile != nil && *commitMsgFile != "" {
-	// 	err := os.WriteFile(*commitMsgFile, []byte(finalCommitMessage), 0644)
-	// 	if err != nil {
-	// 		logger.Fatalf("Error writing commit message to file %s: %v", *commitMsgFile, err)
-	// 	}
-	// } else {
-	// 	fmt.Println(finalCommitMessage)
-	// }
+	commitMessage := finalModel.(application).accepted
+	if err := writeCommitMessage(os.Stdout, *commitMsgFile, commitMessage, existingCommitMessageComments); err != nil {
+		logger.Fatalf("Error writing commit message to file %s: %v", *commitMsgFile, err)
+	}
+}
 
+/*
+readCommitMessageFile returns the message and the comment lines of the commit message file
+that git passes to the hook. A file that cannot be read is treated as empty.
+*/
+func readCommitMessageFile(logger *log.Logger, commitMsgFile string) (message, comments string) {
+	content, err := os.ReadFile(commitMsgFile)
+	if err != nil {
+		logger.Printf("Warning: Could not read existing commit message file %s: %v", commitMsgFile, err)
+		return "", ""
+	}
+
+	message, comments = git.ParseCommitMessage(string(content))
+	return strings.TrimSpace(message), comments
+}
+
+/*
+writeCommitMessage writes the accepted message, followed by the comment lines of the original
+file, to the commit message file, or prints it to w when there is none. Nothing is written when
+the user quit without accepting a message, so git keeps the file untouched.
+*/
+func writeCommitMessage(w io.Writer, commitMsgFile, accepted, comments string) error {
+	if accepted == "" {
+		return nil
+	}
+
+	finalCommitMessage := accepted
+	if comments != "" {
+		finalCommitMessage = fmt.Sprintf("%s\n%s", accepted, comments)
+	}
+
+	if commitMsgFile == "" {
+		_, err := fmt.Fprintln(w, finalCommitMessage)
+		return err
+	}
+	return os.WriteFile(commitMsgFile, []byte(finalCommitMessage), 0644)
 }
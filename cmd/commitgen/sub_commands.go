@@ -1,10 +1,22 @@
 package main
 
 import (
+	"CommitGen/internal/ai"
 	"CommitGen/internal/config"
 	"CommitGen/internal/git"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
 )
 
 // InstallHookFunc installs the git hook by calling git.Install function.
@@ -33,3 +45,278 @@ func GenerateConfigFunc() {
 	}
 	fmt.Println("Config file generated successfully.")
 }
+
+// maskedSecret replaces API keys, header values and URL credentials when the configuration is printed.
+const maskedSecret = "********"
+
+/*
+ConfigFunc prints the effective configuration, after the repository config was layered over the
+user config. With -origin, every value is printed on its own line along with the file that set it.
+The only supported action is "show".
+*/
+func ConfigFunc(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: commitgen config show [-origin]")
+		os.Exit(1)
+	}
+	flags := flag.NewFlagSet("config show", flag.ExitOnError)
+	origin := flags.Bool("origin", false, "Print where each value was set")
+	flags.Parse(args[1:])
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	for _, warning := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	masked := maskSecrets(cfg)
+
+	if !*origin {
+		data, err := toml.Marshal(masked)
+		if err != nil {
+			log.Fatalf("Error encoding configuration: %v", err)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	settings, err := masked.Settings()
+	if err != nil {
+		log.Fatalf("Error listing configuration: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, setting := range settings {
+		fmt.Fprintf(w, "%s = %s\t# %s\n", setting.Key, formatSettingValue(setting.Value), setting.Origin)
+	}
+	w.Flush()
+}
+
+/*
+maskSecrets returns a copy of cfg without its secrets, since the output of 'config show' is easily
+shared in bug reports. API keys and the values of extra headers, which usually carry gateway tokens,
+are masked, and so are the credentials of the proxy and base URLs.
+*/
+func maskSecrets(cfg *config.Config) config.Config {
+	providers := config.ProviderMap{}
+	for providerType, providerCfg := range cfg.AI.Providers {
+		if providerCfg.APIKey != "" {
+			providerCfg.APIKey = maskedSecret
+		}
+		if providerCfg.Headers != nil {
+			headers := make(map[string]string, len(providerCfg.Headers))
+			for name := range providerCfg.Headers {
+				headers[name] = maskedSecret
+			}
+			providerCfg.Headers = headers
+		}
+		providerCfg.Proxy = maskURLCredentials(providerCfg.Proxy)
+		providerCfg.BaseURL = maskURLCredentials(providerCfg.BaseURL)
+		providers[providerType] = providerCfg
+	}
+
+	masked := *cfg
+	masked.AI.Providers = providers
+	return masked
+}
+
+// maskURLCredentials masks the user info of rawURL. A URL that cannot be parsed is masked entirely.
+func maskURLCredentials(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return maskedSecret
+	}
+	if parsed.User == nil {
+		return rawURL
+	}
+
+	// url.User would percent-encode the mask, so it is put in place of the removed user info.
+	parsed.User = nil
+	prefix := parsed.Scheme + "://"
+	return prefix + maskedSecret + "@" + strings.TrimPrefix(parsed.String(), prefix)
+}
+
+// maxSettingValueLength is the length at which long values, such as templates, are cut off by 'config show -origin'.
+const maxSettingValueLength = 60
+
+// formatSettingValue formats a configuration value on a single line, like it is written in TOML.
+func formatSettingValue(value any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+
+	formatted := []rune(strings.TrimSpace(buf.String()))
+	if len(formatted) > maxSettingValueLength {
+		return string(formatted[:maxSettingValueLength-3]) + "..."
+	}
+	return string(formatted)
+}
+
+// CacheFunc manages the local response cache. The only supported action is "clear".
+func CacheFunc(args []string) {
+	if len(args) != 1 || args[0] != "clear" {
+		fmt.Fprintln(os.Stderr, "Usage: commitgen cache clear")
+		os.Exit(1)
+	}
+
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		log.Fatalf("Error locating cache: %v", err)
+	}
+	if err := ai.NewResponseCache(cacheDir, 0).Clear(); err != nil {
+		log.Fatalf("Error clearing cache: %v", err)
+	}
+	fmt.Println("Cache cleared successfully.")
+}
+
+/*
+LearnStyleFunc learns the style profile of the current repository from its commit history and
+saves it in the state directory, where generation picks it up. It prints the learned conventions.
+*/
+func LearnStyleFunc(args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+
+	styleCfg := cfg.Prompt.Style
+	flags := flag.NewFlagSet("learn-style", flag.ExitOnError)
+	flags.IntVar(&styleCfg.Samples, "samples", styleCfg.Samples, "Number of latest commits to sample")
+	flags.IntVar(&styleCfg.Examples, "examples", styleCfg.Examples, "Number of commits to keep as examples")
+	flags.Parse(args)
+
+	profile, path, err := learnStyleProfile(styleCfg)
+	if err != nil {
+		log.Fatalf("Error learning style: %v", err)
+	}
+
+	fmt.Printf("Learned the style of %d commits:\n", profile.Commits)
+	for _, guideline := range profile.Guidelines() {
+		fmt.Printf("- %s\n", guideline)
+	}
+	fmt.Printf("\nKept %d example commits. Profile saved to %s\n", len(profile.Examples), path)
+}
+
+// learnStyleProfile learns the style profile of the current repository and saves it, returning its path.
+func learnStyleProfile(styleCfg config.Style) (ai.StyleProfile, string, error) {
+	path, err := styleProfileFile()
+	if err != nil {
+		return ai.StyleProfile{}, "", err
+	}
+	messages, err := git.GetCommitMessages(styleCfg.Samples)
+	if err != nil {
+		return ai.StyleProfile{}, "", err
+	}
+	profile, err := ai.LearnStyle(messages, styleCfg.Examples)
+	if err != nil {
+		return ai.StyleProfile{}, "", err
+	}
+	return profile, path, profile.Save(path)
+}
+
+/*
+styleProfile returns the saved style profile of the current repository. In automatic mode, a
+missing or stale profile is learned first. Without a profile, an empty one is returned.
+*/
+func styleProfile(styleCfg config.Style) (ai.StyleProfile, error) {
+	if !styleCfg.Enabled {
+		return ai.StyleProfile{}, nil
+	}
+
+	path, err := styleProfileFile()
+	if err != nil {
+		return ai.StyleProfile{}, err
+	}
+	profile, err := ai.LoadStyleProfile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ai.StyleProfile{}, err
+	}
+	if !styleCfg.Auto || (err == nil && !profile.Stale(time.Duration(styleCfg.MaxAge))) {
+		return profile, nil
+	}
+
+	profile, _, err = learnStyleProfile(styleCfg)
+	if errors.Is(err, ai.ErrNoConventionalCommits) {
+		return ai.StyleProfile{}, nil
+	}
+	return profile, err
+}
+
+// styleProfileFile returns the path of the style profile of the current repository.
+func styleProfileFile() (string, error) {
+	root, err := git.FindGitRoot()
+	if err != nil {
+		return "", err
+	}
+	return config.StyleProfileFile(root)
+}
+
+/*
+UsageFunc prints the tokens recorded in the usage ledger, grouped by day, repository and model,
+along with their cost for the models that have a price and today's budget if one is set.
+*/
+func UsageFunc(args []string) {
+	flags := flag.NewFlagSet("usage", flag.ExitOnError)
+	days := flags.Int("days", 30, "Number of days to report, including today")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	ledgerFile, err := config.UsageLedgerFile()
+	if err != nil {
+		log.Fatalf("Error locating usage ledger: %v", err)
+	}
+	records, err := ai.NewUsageLedger(ledgerFile).Records()
+	if err != nil {
+		log.Fatalf("Error reading usage ledger: %v", err)
+	}
+
+	prices := cfg.AI.Usage.Prices
+	now := time.Now()
+	summaries := ai.SummarizeUsage(records, prices, ai.StartOfDay(now).AddDate(0, 0, 1-*days))
+	if len(summaries) == 0 {
+		fmt.Printf("No usage recorded in the last %d days.\n", *days)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tREPO\tMODEL\tCALLS\tPROMPT\tOUTPUT\tTHINKING\tTOTAL\tCOST")
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s/%s\t%s\n", summary.Day, summary.Repo, summary.Provider, summary.Model, usageColumns(summary))
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t%s\n", usageColumns(ai.TotalUsage(summaries)))
+	w.Flush()
+
+	usageCfg := cfg.AI.Usage
+	if usageCfg.DailyTokenBudget > 0 || usageCfg.DailyCostBudget > 0 {
+		today := ai.TotalUsage(ai.SummarizeUsage(records, prices, ai.StartOfDay(now)))
+		fmt.Println()
+		if usageCfg.DailyTokenBudget > 0 {
+			fmt.Printf("Today: %d of %d tokens used.\n", today.TotalTokens, usageCfg.DailyTokenBudget)
+		}
+		if usageCfg.DailyCostBudget > 0 {
+			fmt.Printf("Today: %.4f of %.4f spent.\n", today.Cost, usageCfg.DailyCostBudget)
+		}
+	}
+}
+
+// usageColumns formats the counters of a usage summary as tab-separated columns.
+func usageColumns(summary ai.UsageSummary) string {
+	cost := "-"
+	if summary.Priced {
+		cost = fmt.Sprintf("%.4f", summary.Cost)
+	}
+	return fmt.Sprintf(
+		"%d\t%d\t%d\t%d\t%d\t%s",
+		summary.Calls, summary.PromptTokens, summary.OutputTokens, summary.ThinkingTokens, summary.TotalTokens, cost,
+	)
+}
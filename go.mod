@@ -3,6 +3,7 @@ module CommitGen
 go 1.25.1
 
 require (
+	cloud.google.com/go/auth v0.9.3
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/pelletier/go-toml/v2 v2.2.4
 	google.golang.org/genai v1.25.0
@@ -10,7 +11,6 @@ require (
 
 require (
 	cloud.google.com/go v0.116.0 // indirect
-	cloud.google.com/go/auth v0.9.3 // indirect
 	cloud.google.com/go/compute/metadata v0.5.0 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/charmbracelet/colorprofile v0.2.3-0.20250311203215-f60798e515dc // indirect
@@ -0,0 +1,273 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicDefaultBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+)
+
+// AnthropicProvider implements the LLMProvider interface for interacting with the Anthropic Messages API.
+type AnthropicProvider struct {
+	cfg     *config.Config
+	client  *http.Client
+	baseURL string
+}
+
+// anthropicMessageRequest is the request body sent to the Messages endpoint.
+type anthropicMessageRequest struct {
+	Model       string        `json:"model"`
+	MaxTokens   int32         `json:"max_tokens"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+// anthropicMessageResponse is the subset of the Messages response used by commitgen.
+type anthropicMessageResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+// anthropicContentBlock is a single block of a message's content.
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// anthropicUsage is the token usage reported by the Messages API.
+type anthropicUsage struct {
+	InputTokens  int32 `json:"input_tokens"`
+	OutputTokens int32 `json:"output_tokens"`
+}
+
+/*
+anthropicStreamEvent is a single server-sent event of a streamed Messages response. Only the
+fields of the message_start, content_block_delta, message_delta and error events are decoded.
+*/
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewAnthropicProvider creates and initializes a new AnthropicProvider instance with the given configuration.
+func NewAnthropicProvider(cfg *config.Config) (*AnthropicProvider, error) {
+	providerCfg := cfg.AI.Providers[config.Anthropic]
+	if providerCfg.APIKey == "" {
+		return nil, fmt.Errorf("missing API key for provider %s", config.Anthropic)
+	}
+
+	baseURL := providerCfg.BaseURL
+	if baseURL == "" {
+		baseURL = anthropicDefaultBaseURL
+	}
+	client, err := newHTTPClient(config.Anthropic, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &AnthropicProvider{
+		cfg:     cfg,
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+	return provider, nil
+}
+
+// Capabilities reports the optional features supported by the Anthropic provider.
+func (p AnthropicProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true}
+}
+
+/*
+Generate sends the constructed prompt to the Messages endpoint and returns the generated
+commit message. Stop reasons other than 'end_turn' and 'stop_sequence' are reported as errors.
+*/
+func (p AnthropicProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	// The Messages API requires max_tokens, so fall back to the global value if it is unset.
+	providerCfg := p.cfg.AI.Providers[config.Anthropic]
+	opts := resolveOptions(providerCfg, req.Options)
+	maxTokens := p.cfg.AI.MaxTokens
+	if opts.MaxTokens != nil {
+		maxTokens = *opts.MaxTokens
+	}
+
+	body, err := json.Marshal(anthropicMessageRequest{
+		Model:       providerCfg.Model,
+		MaxTokens:   maxTokens,
+		System:      system,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: opts.Temperature,
+		Stream:      req.Stream != nil,
+	})
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", providerCfg.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	for key, value := range providerCfg.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return GenerateResponse{}, newAPIError(config.Anthropic, resp, respBody)
+	}
+
+	result, err := decodeAnthropicMessage(resp.Body, req.Stream)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	var responseBuilder strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			responseBuilder.WriteString(block.Text)
+		}
+	}
+
+	response := GenerateResponse{
+		Message:      responseBuilder.String(),
+		FinishReason: anthropicFinishReason(result.StopReason),
+		Usage: Usage{
+			PromptTokens: result.Usage.InputTokens,
+			OutputTokens: result.Usage.OutputTokens,
+			TotalTokens:  result.Usage.InputTokens + result.Usage.OutputTokens,
+		},
+		Provider: config.Anthropic,
+		Model:    providerCfg.Model,
+	}
+
+	// Check the stop reason. Anything but a natural stop means the message was truncated or refused.
+	if response.FinishReason != FinishReasonStop {
+		stopped := newStoppedError(response.FinishReason, result.StopReason)
+		stopped.Response = response
+		return GenerateResponse{}, stopped
+	}
+
+	if response.Message == "" {
+		return GenerateResponse{}, fmt.Errorf("AI returned a message with no text content")
+	}
+	return response, nil
+}
+
+/*
+decodeAnthropicMessage reads a Messages response body. When stream is set, the body is a
+stream of events that are merged into a single response while stream receives the text so far.
+*/
+func decodeAnthropicMessage(body io.Reader, stream func(partial string)) (anthropicMessageResponse, error) {
+	var result anthropicMessageResponse
+	if stream == nil {
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return result, fmt.Errorf("could not decode response: %w", err)
+		}
+		return result, nil
+	}
+
+	var text strings.Builder
+	err := readServerSentEvents(body, func(eventName, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("could not decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			result.Usage.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				text.WriteString(event.Delta.Text)
+				stream(text.String())
+			}
+		case "message_delta":
+			result.StopReason = event.Delta.StopReason
+			result.Usage.OutputTokens = event.Usage.OutputTokens
+		case "error":
+			return &APIError{
+				Provider:   config.Anthropic,
+				StatusCode: anthropicStreamErrorStatus(event.Error.Type),
+				Message:    event.Error.Message,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Content = []anthropicContentBlock{{Type: "text", Text: text.String()}}
+	return result, nil
+}
+
+/*
+anthropicStreamErrorStatus maps the error type of an in-stream error event to the HTTP status
+the same error would have had before the stream started, so it is classified consistently.
+*/
+func anthropicStreamErrorStatus(errorType string) int {
+	switch errorType {
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "overloaded_error":
+		return 529
+	case "authentication_error":
+		return http.StatusUnauthorized
+	case "permission_error":
+		return http.StatusForbidden
+	case "api_error":
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// anthropicFinishReason maps a Messages API stop reason onto the provider-independent FinishReason.
+func anthropicFinishReason(reason string) FinishReason {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return FinishReasonStop
+	case "max_tokens":
+		return FinishReasonMaxTokens
+	case "refusal":
+		return FinishReasonSafety
+	}
+	return FinishReasonOther
+}
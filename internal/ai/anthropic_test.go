@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// anthropicTestConfig is the [ai.providers.anthropic] table of the Anthropic provider tests.
+var anthropicTestConfig = config.ProviderConfig{
+	APIKey: "test-anthropic-key",
+	Model:  "claude-test",
+}
+
+func TestAnthropicGenerate_Success(t *testing.T) {
+	provider := setupTestProvider(t, config.Anthropic, anthropicTestConfig, NewAnthropicProvider, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		if key := r.Header.Get("x-api-key"); key != "test-anthropic-key" {
+			t.Errorf("unexpected x-api-key header %q", key)
+		}
+		if version := r.Header.Get("anthropic-version"); version == "" {
+			t.Errorf("expected an anthropic-version header")
+		}
+
+		var req anthropicMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.MaxTokens != 4096 {
+			t.Errorf("expected max_tokens 4096, got %d", req.MaxTokens)
+		}
+		if req.Temperature == nil || *req.Temperature != 0.3 {
+			t.Errorf("expected temperature to fall back to the global 0.3")
+		}
+		if !strings.Contains(req.System, "conventional commit messages") || len(req.Messages) != 1 {
+			t.Errorf("expected the system template in the system field, got %q", req.System)
+		}
+
+		w.Write([]byte(`{"content":[{"type":"text","text":"refactor: Extract "},{"type":"text","text":"helper"}],"stop_reason":"end_turn"}`))
+	})
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "refactor: Extract helper" {
+		t.Errorf("expected 'refactor: Extract helper', got %q", resp.Message)
+	}
+}
+
+func TestAnthropicGenerate_StopReasonMaxTokens(t *testing.T) {
+	provider := setupTestProvider(t, config.Anthropic, anthropicTestConfig, NewAnthropicProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content":[{"type":"text","text":"refactor: Ex"}],"stop_reason":"max_tokens"}`))
+	})
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("expected a stop reason error, got %v", err)
+	}
+}
+
+func TestAnthropicGenerate_APIError(t *testing.T) {
+	provider := setupTestProvider(t, config.Anthropic, anthropicTestConfig, NewAnthropicProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`))
+	})
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", apiErr.StatusCode)
+	}
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"CommitGen/internal/git"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TokenCounter is implemented by providers that can count the tokens of a prompt exactly.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, prompt string) (int32, error)
+}
+
+// heuristicCounter is the TokenCounter of providers that cannot count tokens themselves.
+type heuristicCounter struct{}
+
+func (heuristicCounter) CountTokens(ctx context.Context, prompt string) (int32, error) {
+	return estimateTokens(prompt), nil
+}
+
+// estimateTokens estimates the token count of text at roughly four characters per token.
+func estimateTokens(text string) int32 {
+	return int32((len(text) + 3) / 4)
+}
+
+/*
+BudgetProvider implements the LLMProvider interface by shrinking the staged diff of every
+request until the rendered prompt fits the provider's max_prompt_tokens. Tokens are counted
+by the provider itself when it implements TokenCounter, and estimated otherwise.
+*/
+type BudgetProvider struct {
+	cfg       *config.Config
+	provider  LLMProvider
+	counter   TokenCounter
+	maxTokens int32
+}
+
+/*
+NewBudgetProvider wraps provider so that its prompts are truncated to maxTokens, following
+the configured truncation order.
+*/
+func NewBudgetProvider(cfg *config.Config, provider LLMProvider, maxTokens int32) (*BudgetProvider, error) {
+	for _, strategy := range cfg.AI.Truncation.Order {
+		switch strategy {
+		case config.TruncateGenerated, config.TruncateHunks, config.TruncateStat:
+		default:
+			return nil, fmt.Errorf("unsupported truncation strategy: %q", strategy)
+		}
+	}
+
+	counter, ok := provider.(TokenCounter)
+	if !ok {
+		counter = heuristicCounter{}
+	}
+
+	budgetProvider := &BudgetProvider{
+		cfg:       cfg,
+		provider:  provider,
+		counter:   counter,
+		maxTokens: maxTokens,
+	}
+	return budgetProvider, nil
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p BudgetProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+// Generate fits the request's prompt data into the token budget and forwards it to the wrapped provider.
+func (p BudgetProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	data, err := p.fit(ctx, req.PromptData)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+	req.PromptData = data
+	return p.provider.Generate(ctx, req)
+}
+
+/*
+fit applies the truncation strategies in order until the prompt fits the budget, recording
+what was left out in the TruncationNote of the prompt data. It fails if the prompt is still
+too large once every strategy was applied.
+*/
+func (p BudgetProvider) fit(ctx context.Context, data PromptData) (PromptData, error) {
+	tokens, err := p.count(ctx, data)
+	if err != nil || tokens <= p.maxTokens {
+		return data, err
+	}
+
+	// Summaries and other text that is not a diff cannot be truncated file by file.
+	files := git.ParseDiff(data.StagedDiff)
+	if len(files) == 0 {
+		return data, fmt.Errorf("prompt needs %d tokens, which exceeds max_prompt_tokens (%d)", tokens, p.maxTokens)
+	}
+
+	var hunkHeadersOnly, statOnly bool
+	var notes []string
+	for _, strategy := range p.cfg.AI.Truncation.Order {
+		switch strategy {
+		case config.TruncateGenerated:
+			var omitted []string
+			files, omitted = dropGeneratedFiles(files, p.cfg.AI.Truncation.GeneratedFiles)
+			if len(omitted) == 0 {
+				continue
+			}
+			notes = append(notes, fmt.Sprintf("the diffs of generated files were omitted (%s)", strings.Join(omitted, ", ")))
+		case config.TruncateHunks:
+			hunkHeadersOnly = true
+			notes = append(notes, "the content of every hunk was omitted, only the hunk headers are shown")
+		case config.TruncateStat:
+			statOnly = true
+			notes = append(notes, "only a summary of the changed files is shown")
+		}
+
+		data.StagedDiff = renderDiff(files, hunkHeadersOnly, statOnly)
+		data.TruncationNote = strings.Join(notes, "; ")
+		tokens, err = p.count(ctx, data)
+		if err != nil || tokens <= p.maxTokens {
+			return data, err
+		}
+	}
+	return data, fmt.Errorf("prompt needs %d tokens, which exceeds max_prompt_tokens (%d) even after truncation", tokens, p.maxTokens)
+}
+
+// count renders the system instruction and the prompt for data and returns their number of tokens.
+func (p BudgetProvider) count(ctx context.Context, data PromptData) (int32, error) {
+	system, prompt, err := buildPrompts(p.cfg, data)
+	if err != nil {
+		return 0, err
+	}
+	prompt = joinPrompts(system, prompt)
+
+	tokens, err := p.counter.CountTokens(ctx, prompt)
+	if err != nil {
+		// An exact count is not worth failing the generation for.
+		return heuristicCounter{}.CountTokens(ctx, prompt)
+	}
+	return tokens, nil
+}
+
+// dropGeneratedFiles removes the files matching one of the patterns and returns their paths.
+func dropGeneratedFiles(files []git.FileDiff, patterns []string) (kept []git.FileDiff, omitted []string) {
+	for _, file := range files {
+		if file.MatchesAny(patterns) {
+			omitted = append(omitted, file.Path)
+		} else {
+			kept = append(kept, file)
+		}
+	}
+	return kept, omitted
+}
+
+// renderDiff turns the parsed files back into a diff, a diff of hunk headers or a stat summary.
+func renderDiff(files []git.FileDiff, hunkHeadersOnly, statOnly bool) string {
+	if statOnly {
+		return git.DiffStat(files)
+	}
+
+	var diff strings.Builder
+	for _, file := range files {
+		if hunkHeadersOnly {
+			diff.WriteString(file.HunkHeaders())
+		} else {
+			diff.WriteString(file.String())
+		}
+	}
+	return diff.String()
+}
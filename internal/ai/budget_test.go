@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"strings"
+	"testing"
+)
+
+// recordingProvider is an LLMProvider that remembers the last request it received.
+type recordingProvider struct {
+	req GenerateRequest
+}
+
+func (p *recordingProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	p.req = req
+	return GenerateResponse{Message: "chore: Update dependencies"}, nil
+}
+
+func (p *recordingProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// budgetTestDiff returns a staged diff with a large lock file and a source file with large hunks.
+func budgetTestDiff() string {
+	return "diff --git a/package-lock.json b/package-lock.json\n" +
+		"--- a/package-lock.json\n+++ b/package-lock.json\n" +
+		"@@ -1,1 +1,1 @@\n" + strings.Repeat("+\"dependency\": \"1.0.0\",\n", 2000) +
+		"diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1,1 +1,1 @@ func main() {\n" + strings.Repeat("+\tfmt.Println(\"hello\")\n", 400)
+}
+
+func TestBudgetGenerate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		maxTokens     int32
+		expectedNote  string
+		expectInDiff  string
+		expectMissing string
+		expectErr     bool
+	}{
+		{
+			name:         "prompt within budget is untouched",
+			maxTokens:    100000,
+			expectInDiff: "\"dependency\"",
+		},
+		{
+			name:          "generated files are dropped first",
+			maxTokens:     5000,
+			expectedNote:  "generated files were omitted (package-lock.json)",
+			expectInDiff:  "fmt.Println",
+			expectMissing: "\"dependency\"",
+		},
+		{
+			name:          "hunks are shrunk to their headers",
+			maxTokens:     1500,
+			expectedNote:  "only the hunk headers are shown",
+			expectInDiff:  "@@ -1,1 +1,1 @@ func main() {",
+			expectMissing: "fmt.Println",
+		},
+		{
+			name:      "prompt that never fits is an error",
+			maxTokens: 10,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := setupTestConfig()
+			inner := &recordingProvider{}
+			provider, err := NewBudgetProvider(cfg, inner, tc.maxTokens)
+			if err != nil {
+				t.Fatalf("NewBudgetProvider failed: %v", err)
+			}
+
+			_, err = provider.Generate(context.Background(), newTestRequest(budgetTestDiff()))
+			if tc.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "max_prompt_tokens") {
+					t.Errorf("expected a budget error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+
+			data := inner.req.PromptData
+			if !strings.Contains(data.TruncationNote, tc.expectedNote) {
+				t.Errorf("expected the truncation note to contain %q, got %q", tc.expectedNote, data.TruncationNote)
+			}
+			if !strings.Contains(data.StagedDiff, tc.expectInDiff) {
+				t.Errorf("expected the diff to still contain %q", tc.expectInDiff)
+			}
+			if tc.expectMissing != "" && strings.Contains(data.StagedDiff, tc.expectMissing) {
+				t.Errorf("expected %q to be truncated from the diff", tc.expectMissing)
+			}
+
+			prompt, _ := BuildPrompt(cfg, data)
+			if tc.expectedNote != "" && !strings.Contains(prompt, "the staged diff was truncated") {
+				t.Errorf("expected the prompt to mention the truncation")
+			}
+		})
+	}
+}
+
+func TestBudgetGenerate_StatOnly(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Truncation.Order = []string{config.TruncateStat}
+	inner := &recordingProvider{}
+	provider, _ := NewBudgetProvider(cfg, inner, 1500)
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(budgetTestDiff())); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "2 files changed, 2400 insertions(+), 0 deletions(-)"
+	if !strings.Contains(inner.req.PromptData.StagedDiff, expected) {
+		t.Errorf("expected a stat summary, got %q", inner.req.PromptData.StagedDiff)
+	}
+}
+
+func TestNewBudgetProvider_UnknownStrategy(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Truncation.Order = []string{"summarize"}
+
+	if _, err := NewBudgetProvider(cfg, &recordingProvider{}, 1000); err == nil {
+		t.Fatal("expected an error for an unknown truncation strategy, got nil")
+	}
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+ResponseCache stores generated responses as JSON files in a directory, one file per key.
+Entries older than the TTL are treated as missing.
+*/
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cacheEntry is the content of a single cache file.
+type cacheEntry struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Response  GenerateResponse `json:"response"`
+}
+
+// NewResponseCache creates a ResponseCache that keeps its entries in dir for ttl.
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		dir: dir,
+		ttl: ttl,
+	}
+}
+
+// Get returns the response stored under key, if there is one that has not expired.
+func (c ResponseCache) Get(key string) (GenerateResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return GenerateResponse{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return GenerateResponse{}, false
+	}
+	if c.ttl > 0 && time.Since(entry.CreatedAt) > c.ttl {
+		return GenerateResponse{}, false
+	}
+	return entry.Response, true
+}
+
+// Put stores resp under key, replacing any previous entry.
+func (c ResponseCache) Put(key string, resp GenerateResponse) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("could not create cache directory at %s: %w", c.dir, err)
+	}
+
+	data, err := json.Marshal(cacheEntry{CreatedAt: time.Now(), Response: resp})
+	if err != nil {
+		return fmt.Errorf("could not encode cache entry: %w", err)
+	}
+	if err := writeFileAtomic(c.path(key), "entry-*.tmp", data); err != nil {
+		return fmt.Errorf("could not write cache entry: %w", err)
+	}
+	return nil
+}
+
+/*
+writeFileAtomic replaces the file at path with data. The data is written to a temporary file
+named after pattern in the same directory first and then renamed, so a concurrent reader never
+sees a partial file.
+*/
+func writeFileAtomic(path, pattern string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), pattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Clear removes every cached response.
+func (c ResponseCache) Clear() error {
+	if err := os.RemoveAll(c.dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove cache directory at %s: %w", c.dir, err)
+	}
+	return nil
+}
+
+func (c ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+/*
+CacheProvider implements the LLMProvider interface by serving repeated requests from a
+ResponseCache. Requests are keyed by the rendered prompt, the default provider and model,
+and the generation parameters, so any change to the diff or the settings is a cache miss.
+*/
+type CacheProvider struct {
+	cfg      *config.Config
+	provider LLMProvider
+	cache    *ResponseCache
+}
+
+// NewCacheProvider wraps provider so that its responses are stored in and served from cache.
+func NewCacheProvider(cfg *config.Config, provider LLMProvider, cache *ResponseCache) *CacheProvider {
+	return &CacheProvider{
+		cfg:      cfg,
+		provider: provider,
+		cache:    cache,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p CacheProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate returns the cached response for the request if there is one, and otherwise calls the
+wrapped provider and caches its successful response. Responses that were cut off, e.g. by
+max_tokens, are not cached, so the next run generates them again. Neither are responses of a
+fallback provider, as the key names the default provider. Cache failures never fail a generation.
+*/
+func (p CacheProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	key, err := p.key(req)
+	if err != nil {
+		return p.provider.Generate(ctx, req)
+	}
+
+	if resp, ok := p.cache.Get(key); ok {
+		resp.Cached = true
+		return resp, nil
+	}
+
+	resp, err := p.provider.Generate(ctx, req)
+	if err == nil && resp.FinishReason == FinishReasonStop && len(resp.Fallbacks) == 0 {
+		_ = p.cache.Put(key, resp)
+	}
+	return resp, err
+}
+
+// cacheKey holds everything that influences the response to a request.
+type cacheKey struct {
+	System       string
+	Prompt       string
+	Provider     config.ProviderType
+	Model        string
+	Options      GenerateOptions
+	OutputFormat string
+}
+
+// key returns the hex-encoded SHA-256 hash identifying the request.
+func (p CacheProvider) key(req GenerateRequest) (string, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return "", err
+	}
+
+	providerCfg := p.cfg.AI.Providers[p.cfg.AI.DefaultProvider]
+	data, err := json.Marshal(cacheKey{
+		System:       system,
+		Prompt:       prompt,
+		Provider:     p.cfg.AI.DefaultProvider,
+		Model:        providerCfg.Model,
+		Options:      resolveOptions(providerCfg, req.Options),
+		OutputFormat: p.cfg.AI.OutputFormat,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
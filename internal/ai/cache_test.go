@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheProvider(t *testing.T) {
+	cfg := setupTestConfig()
+	inner := &countingProvider{}
+	provider := NewCacheProvider(cfg, inner, NewResponseCache(t.TempDir(), time.Hour))
+
+	first, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("expected the first response not to be cached")
+	}
+
+	second, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !second.Cached || second.Message != first.Message || second.Usage != first.Usage {
+		t.Errorf("expected the cached response %+v, got %+v", first, second)
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected a repeated request to be served from the cache, got %d calls", inner.calls)
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(req *GenerateRequest)
+	}{
+		{
+			name:   "different diff",
+			modify: func(req *GenerateRequest) { req.PromptData.StagedDiff += "\n+// more" },
+		},
+		{
+			name: "different temperature",
+			modify: func(req *GenerateRequest) {
+				temperature := float32(0.9)
+				req.Options.Temperature = &temperature
+			},
+		},
+		{
+			name:   "different candidate count",
+			modify: func(req *GenerateRequest) { req.Options.Candidates = 3 },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := inner.calls
+			req := newTestRequest(stagedDiff)
+			tc.modify(&req)
+
+			resp, err := provider.Generate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Cached || inner.calls != calls+1 {
+				t.Errorf("expected a cache miss, got cached = %v after %d calls", resp.Cached, inner.calls-calls)
+			}
+		})
+	}
+}
+
+func TestCacheProvider_ModelChange(t *testing.T) {
+	cfg := setupTestConfig()
+	inner := &countingProvider{}
+	provider := NewCacheProvider(cfg, inner, NewResponseCache(t.TempDir(), time.Hour))
+
+	provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	providerCfg := cfg.AI.Providers[cfg.AI.DefaultProvider]
+	providerCfg.Model = "gemini-ultra"
+	cfg.AI.Providers[cfg.AI.DefaultProvider] = providerCfg
+
+	if resp, _ := provider.Generate(context.Background(), newTestRequest(stagedDiff)); resp.Cached {
+		t.Error("expected a different model to miss the cache")
+	}
+}
+
+func TestCacheProvider_FailuresNotCached(t *testing.T) {
+	cfg := setupTestConfig()
+	inner := &countingProvider{failCalls: map[int]bool{1: true}}
+	provider := NewCacheProvider(cfg, inner, NewResponseCache(t.TempDir(), time.Hour))
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err == nil {
+		t.Fatal("expected the first call to fail")
+	}
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil || resp.Cached {
+		t.Errorf("expected a failure not to be cached, got cached = %v, err = %v", resp.Cached, err)
+	}
+}
+
+func TestCacheProvider_CutOffNotCached(t *testing.T) {
+	cfg := setupTestConfig()
+	// MaxTokensProvider returns a message cut off at the max_tokens limit as a success.
+	inner := &stubProvider{resp: GenerateResponse{Message: "feat: Add", FinishReason: FinishReasonMaxTokens}}
+	provider := NewCacheProvider(cfg, inner, NewResponseCache(t.TempDir(), time.Hour))
+
+	for range 2 {
+		resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if resp.Cached {
+			t.Error("expected a cut off response not to be cached")
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("expected every request to call the provider, got %d calls", inner.calls)
+	}
+}
+
+func TestCacheProvider_FallbackNotCached(t *testing.T) {
+	cfg := setupTestConfig()
+	primary := &stubProvider{err: context.DeadlineExceeded}
+	secondary := &stubProvider{resp: GenerateResponse{Message: "secondary", Provider: config.OpenAI, FinishReason: FinishReasonStop}}
+	fallback, err := NewFallbackProvider(
+		[]LLMProvider{primary, secondary},
+		[]config.ProviderType{config.Gemini, config.OpenAI},
+	)
+	if err != nil {
+		t.Fatalf("NewFallbackProvider failed: %v", err)
+	}
+	provider := NewCacheProvider(cfg, fallback, NewResponseCache(t.TempDir(), time.Hour))
+
+	for range 2 {
+		resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if resp.Cached {
+			t.Error("expected the answer of a fallback provider not to be cached under the default provider")
+		}
+	}
+	if primary.calls != 2 {
+		t.Errorf("expected every request to try the default provider again, got %d calls", primary.calls)
+	}
+}
+
+func TestResponseCache_Expiry(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	if err := cache.Put("key", GenerateResponse{Message: "feat: Add cache"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if resp, ok := cache.Get("key"); !ok || resp.Message != "feat: Add cache" {
+		t.Errorf("expected the stored response, got %+v (found = %v)", resp, ok)
+	}
+
+	cache.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("key"); ok {
+		t.Error("expected an expired entry to be missing")
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	cache.ttl = time.Hour
+	if _, ok := cache.Get("key"); ok {
+		t.Error("expected Clear to remove every entry")
+	}
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+CandidatesProvider implements the LLMProvider interface for providers that can only generate a
+single message per call. When several candidates are requested, it calls the wrapped provider
+once per candidate in parallel and merges the results into one response.
+*/
+type CandidatesProvider struct {
+	provider LLMProvider
+}
+
+// NewCandidatesProvider wraps provider so that it can serve requests for several candidates.
+func NewCandidatesProvider(provider LLMProvider) *CandidatesProvider {
+	return &CandidatesProvider{
+		provider: provider,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider, which now include multiple candidates.
+func (p CandidatesProvider) Capabilities() Capabilities {
+	capabilities := p.provider.Capabilities()
+	capabilities.MultipleCandidates = true
+	return capabilities
+}
+
+/*
+Generate forwards single-candidate requests unchanged. Otherwise it runs one request per
+candidate, streaming only the first one, and succeeds as long as at least one of them does.
+The token usage of all calls is added up.
+*/
+func (p CandidatesProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	count := req.Options.Candidates
+	if count <= 1 {
+		return p.provider.Generate(ctx, req)
+	}
+
+	responses := make([]GenerateResponse, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	for i := range count {
+		single := req
+		single.Options.Candidates = 1
+		if i > 0 {
+			single.Stream = nil
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = p.provider.Generate(ctx, single)
+		}()
+	}
+	wg.Wait()
+
+	var merged GenerateResponse
+	var firstErr error
+	for i, resp := range responses {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+
+		if len(merged.Candidates) == 0 {
+			merged = resp
+			merged.Candidates = nil
+			merged.CommitMessages = nil
+			merged.Usage = Usage{}
+		}
+		// A single cut off candidate marks the whole response as cut off.
+		if resp.FinishReason != FinishReasonStop {
+			merged.FinishReason = resp.FinishReason
+		}
+		merged.Candidates = append(merged.Candidates, resp.Message)
+		merged.CommitMessages = append(merged.CommitMessages, resp.CommitMessages...)
+		merged.Usage.PromptTokens += resp.Usage.PromptTokens
+		merged.Usage.OutputTokens += resp.Usage.OutputTokens
+		merged.Usage.ThinkingTokens += resp.Usage.ThinkingTokens
+		merged.Usage.TotalTokens += resp.Usage.TotalTokens
+	}
+
+	if len(merged.Candidates) == 0 {
+		return GenerateResponse{}, firstErr
+	}
+	return merged, nil
+}
@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+)
+
+/*
+countingProvider is an LLMProvider that numbers its responses, fails the calls listed in
+failCalls and cuts off the responses of the calls listed in cutOffCalls. It is safe for
+concurrent use.
+*/
+type countingProvider struct {
+	mu          sync.Mutex
+	calls       int
+	failCalls   map[int]bool
+	cutOffCalls map[int]bool
+	requests    []GenerateRequest
+}
+
+func (p *countingProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	call := p.calls
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+
+	if p.failCalls[call] {
+		return GenerateResponse{}, &APIError{StatusCode: http.StatusInternalServerError}
+	}
+	finishReason := FinishReasonStop
+	if p.cutOffCalls[call] {
+		finishReason = FinishReasonMaxTokens
+	}
+	return GenerateResponse{
+		Message:      fmt.Sprintf("feat: Candidate %d", call),
+		FinishReason: finishReason,
+		Usage:        Usage{PromptTokens: 10, OutputTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func (p *countingProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true}
+}
+
+func TestCandidatesGenerate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		candidates    int
+		failCalls     map[int]bool
+		expectedCalls int
+		expectedCount int
+		expectErr     bool
+	}{
+		{name: "single candidate is forwarded", candidates: 1, expectedCalls: 1},
+		{name: "three candidates in parallel", candidates: 3, expectedCalls: 3, expectedCount: 3},
+		{name: "partial failure keeps successes", candidates: 3, failCalls: map[int]bool{2: true}, expectedCalls: 3, expectedCount: 2},
+		{name: "all calls fail", candidates: 2, failCalls: map[int]bool{1: true, 2: true}, expectedCalls: 2, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &countingProvider{failCalls: tc.failCalls}
+			provider := NewCandidatesProvider(inner)
+
+			req := newTestRequest(stagedDiff)
+			req.Options.Candidates = tc.candidates
+			resp, err := provider.Generate(context.Background(), req)
+
+			if inner.calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, inner.calls)
+			}
+			if tc.expectErr {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Errorf("expected the first call's error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+
+			if len(resp.Candidates) != tc.expectedCount {
+				t.Fatalf("expected %d candidates, got %q", tc.expectedCount, resp.Candidates)
+			}
+			if tc.expectedCount > 0 {
+				if resp.Message != resp.Candidates[0] {
+					t.Errorf("expected Message to be the first candidate, got %q", resp.Message)
+				}
+				if resp.Usage.TotalTokens != int32(15*tc.expectedCount) {
+					t.Errorf("expected usage to be summed, got %+v", resp.Usage)
+				}
+				for _, r := range inner.requests {
+					if r.Options.Candidates != 1 {
+						t.Errorf("expected every parallel call to request a single candidate, got %d", r.Options.Candidates)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCandidatesGenerate_CutOff(t *testing.T) {
+	inner := &countingProvider{cutOffCalls: map[int]bool{2: true}}
+	provider := NewCandidatesProvider(inner)
+
+	req := newTestRequest(stagedDiff)
+	req.Options.Candidates = 3
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.FinishReason != FinishReasonMaxTokens {
+		t.Errorf("expected a cut off candidate to mark the response as cut off, got %q", resp.FinishReason)
+	}
+}
+
+func TestCandidatesGenerate_StreamsFirstCallOnly(t *testing.T) {
+	inner := &countingProvider{}
+	provider := NewCandidatesProvider(inner)
+
+	req := newTestRequest(stagedDiff)
+	req.Options.Candidates = 3
+	req.Stream = func(string) {}
+	if _, err := provider.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	streaming := slices.DeleteFunc(inner.requests, func(r GenerateRequest) bool { return r.Stream == nil })
+	if len(streaming) != 1 {
+		t.Errorf("expected exactly one streaming call, got %d", len(streaming))
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// commitBodyWidth is the column at which body bullet points are wrapped.
+const commitBodyWidth = 72
+
+/*
+CommitMessage is a conventional commit message as returned by a provider in JSON mode.
+commitgen formats it itself, so the model never has to get the layout right.
+*/
+type CommitMessage struct {
+	Type     string   `json:"type"`
+	Scope    string   `json:"scope"`
+	Subject  string   `json:"subject"`
+	Body     []string `json:"body"`
+	Breaking bool     `json:"breaking"`
+	Footers  []Footer `json:"footers"`
+}
+
+// Footer is a single git trailer of a commit message, such as 'Refs: #123'.
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+/*
+commitMessageSchema is the JSON schema of CommitMessage sent to providers that constrain their
+output to a schema. Every property is required so it also satisfies OpenAI's strict mode.
+*/
+var commitMessageSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"type":    map[string]any{"type": "string", "description": "The conventional commit type, e.g. 'feat' or 'fix'."},
+		"scope":   map[string]any{"type": "string", "description": "The optional scope of the change, or an empty string."},
+		"subject": map[string]any{"type": "string", "description": "A short imperative summary starting with a capital letter."},
+		"body": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "Bullet points explaining the change, without leading dashes.",
+		},
+		"breaking": map[string]any{"type": "boolean", "description": "Whether the change breaks backwards compatibility."},
+		"footers": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"token": map[string]any{"type": "string"},
+					"value": map[string]any{"type": "string"},
+				},
+				"required":             []string{"token", "value"},
+				"additionalProperties": false,
+			},
+			"description": "Git trailers such as 'Refs' or 'BREAKING CHANGE'.",
+		},
+	},
+	"required":             []string{"type", "scope", "subject", "body", "breaking", "footers"},
+	"additionalProperties": false,
+}
+
+/*
+ParseCommitMessage decodes a JSON commit message returned by a provider. A surrounding
+markdown code fence is tolerated, since some models add one even in JSON mode.
+*/
+func ParseCommitMessage(raw string) (CommitMessage, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "```") {
+		raw = strings.TrimPrefix(raw, "```json")
+		raw = strings.TrimPrefix(raw, "```")
+		raw = strings.TrimSuffix(raw, "```")
+	}
+
+	var message CommitMessage
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		return CommitMessage{}, fmt.Errorf("could not parse JSON commit message: %w", err)
+	}
+
+	message.Type = strings.TrimSpace(message.Type)
+	message.Scope = strings.Trim(strings.TrimSpace(message.Scope), "()")
+	message.Subject = strings.TrimSpace(message.Subject)
+	if message.Type == "" || message.Subject == "" {
+		return CommitMessage{}, fmt.Errorf("JSON commit message is missing its type or subject")
+	}
+	return message, nil
+}
+
+// Header returns the first line of the commit message, e.g. 'feat(ai)!: Add JSON mode'.
+func (m CommitMessage) Header() string {
+	var header strings.Builder
+	header.WriteString(m.Type)
+	if m.Scope != "" {
+		header.WriteString("(" + m.Scope + ")")
+	}
+	if m.Breaking {
+		header.WriteString("!")
+	}
+	header.WriteString(": " + m.Subject)
+	return header.String()
+}
+
+/*
+String formats the commit message: the header, a blank line, the body as dash bullet points
+wrapped at 72 columns, and finally the footers.
+*/
+func (m CommitMessage) String() string {
+	sections := []string{m.Header()}
+
+	var bullets []string
+	for _, bullet := range m.Body {
+		bullet = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(bullet), "-*"))
+		if bullet != "" {
+			bullets = append(bullets, wrapBullet(bullet, commitBodyWidth))
+		}
+	}
+	if len(bullets) > 0 {
+		sections = append(sections, strings.Join(bullets, "\n"))
+	}
+
+	var footers []string
+	for _, footer := range m.Footers {
+		if footer.Token != "" && footer.Value != "" {
+			footers = append(footers, footer.Token+": "+footer.Value)
+		}
+	}
+	if len(footers) > 0 {
+		sections = append(sections, strings.Join(footers, "\n"))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// wrapBullet formats text as a '- ' bullet point, wrapping it at width with a hanging indent.
+func wrapBullet(text string, width int) string {
+	var lines []string
+	line := "-"
+	for _, word := range strings.Fields(text) {
+		if len(line) > 2 && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = " "
+		}
+		line += " " + word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
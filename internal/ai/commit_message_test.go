@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseCommitMessage(t *testing.T) {
+	testCases := []struct {
+		name      string
+		raw       string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "header only",
+			raw:      `{"type":"fix","scope":"","subject":"Handle nil config","body":[],"breaking":false,"footers":[]}`,
+			expected: "fix: Handle nil config",
+		},
+		{
+			name: "scope, body, breaking flag and footers",
+			raw: `{"type":"feat","scope":"ai","subject":"Add JSON mode","body":["- Parse the model output into a CommitMessage","Format it in commitgen"],` +
+				`"breaking":true,"footers":[{"token":"Refs","value":"#12"}]}`,
+			expected: "feat(ai)!: Add JSON mode\n\n" +
+				"- Parse the model output into a CommitMessage\n- Format it in commitgen\n\n" +
+				"Refs: #12",
+		},
+		{
+			name:     "markdown fence is tolerated",
+			raw:      "```json\n{\"type\":\"docs\",\"scope\":\"(readme)\",\"subject\":\"Document JSON mode\"}\n```",
+			expected: "docs(readme): Document JSON mode",
+		},
+		{
+			name:      "missing subject",
+			raw:       `{"type":"feat","subject":" "}`,
+			expectErr: true,
+		},
+		{
+			name:      "not JSON",
+			raw:       "feat: Add JSON mode",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := ParseCommitMessage(tc.raw)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCommitMessage failed: %v", err)
+			}
+			if got := message.String(); got != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCommitMessageString_WrapsBody(t *testing.T) {
+	message := CommitMessage{
+		Type:    "refactor",
+		Subject: "Split the provider wrappers",
+		Body:    []string{strings.Repeat("word ", 30)},
+	}
+
+	lines := strings.Split(message.String(), "\n")
+	for _, line := range lines[2:] {
+		if len(line) > commitBodyWidth {
+			t.Errorf("expected body lines to be wrapped at %d columns, got %q", commitBodyWidth, line)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "- word") || !strings.HasPrefix(lines[3], "  word") {
+		t.Errorf("expected a bullet with a hanging indent, got %q", lines[2:])
+	}
+}
+
+func TestStructuredGenerate_OpenAI(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_schema" {
+			t.Errorf("expected a json_schema response format, got %+v", req.ResponseFormat)
+		}
+		if strings.Contains(req.Messages[0].Content, "without any markdown formatting") {
+			t.Errorf("expected the prompt to ask for JSON instead of a raw message")
+		}
+
+		content, _ := json.Marshal(`{"type":"fix","scope":"git","subject":"Quote paths in the hook","body":["Paths with spaces broke the hook"],"breaking":false,"footers":[]}`)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":` + string(content) + `},"finish_reason":"stop"}]}`))
+	})
+
+	resp, err := NewStructuredProvider(provider).Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "fix(git): Quote paths in the hook\n\n- Paths with spaces broke the hook"
+	if resp.Message != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, resp.Message)
+	}
+	if len(resp.CommitMessages) != 1 || resp.CommitMessages[0].Scope != "git" {
+		t.Errorf("expected the parsed commit message to be returned, got %+v", resp.CommitMessages)
+	}
+}
+
+func TestGetProvider_OutputFormat(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.OpenAI
+	cfg.AI.Providers[config.OpenAI] = config.ProviderConfig{APIKey: "test-openai-key", Model: "gpt-test"}
+	cfg.AI.Retry.MaxAttempts = 1
+
+	cfg.AI.OutputFormat = config.OutputFormatJSON
+	provider, err := GetProvider(cfg)
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if _, ok := provider.(*StructuredProvider); !ok {
+		t.Errorf("expected output_format = json to wrap the provider, got %T", provider)
+	}
+
+	cfg.AI.OutputFormat = "yaml"
+	if _, err := GetProvider(cfg); err == nil {
+		t.Error("expected an error for an unsupported output_format, got nil")
+	}
+}
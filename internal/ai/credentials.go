@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// keyringService is the service name API keys are stored under in the OS keyring.
+	keyringService = "commitgen"
+
+	// keyCommandTimeout bounds key commands, which may wait for a passphrase or an unlock prompt.
+	keyCommandTimeout = 2 * time.Minute
+)
+
+/*
+KeySource is a place the API key of a provider can be read from instead of a plaintext api_key
+in the config file. Lookup reports whether the source is configured for the provider at all.
+*/
+type KeySource interface {
+	Name() string
+	Lookup(ctx context.Context, providerType config.ProviderType, providerCfg config.ProviderConfig) (string, bool, error)
+}
+
+// keySources are the sources tried for a provider without a literal api_key, in order of precedence.
+var keySources = []KeySource{EnvKeySource{}, CommandKeySource{}, KeyringKeySource{}}
+
+/*
+resolveAPIKey fills in the API key of providerType from the first key source configured for it.
+A literal api_key, from the config file or the --api-key flag, takes precedence over all sources.
+*/
+func resolveAPIKey(cfg *config.Config, providerType config.ProviderType) error {
+	providerCfg := cfg.AI.Providers[providerType]
+	if providerCfg.APIKey != "" {
+		return nil
+	}
+
+	for _, source := range keySources {
+		key, ok, err := source.Lookup(context.TODO(), providerType, providerCfg)
+		if err != nil {
+			return fmt.Errorf("could not read the API key of provider %s from %s: %w", providerType, source.Name(), err)
+		}
+		if !ok {
+			continue
+		}
+		if key == "" {
+			return fmt.Errorf("the API key of provider %s from %s is empty", providerType, source.Name())
+		}
+
+		providerCfg.APIKey = key
+		cfg.AI.Providers[providerType] = providerCfg
+		return nil
+	}
+	return nil
+}
+
+// EnvKeySource reads the API key from the environment variable named by api_key_env.
+type EnvKeySource struct{}
+
+// Name identifies the source in error messages.
+func (EnvKeySource) Name() string {
+	return "api_key_env"
+}
+
+// Lookup returns the value of the environment variable, which must be set.
+func (EnvKeySource) Lookup(ctx context.Context, providerType config.ProviderType, providerCfg config.ProviderConfig) (string, bool, error) {
+	if providerCfg.APIKeyEnv == "" {
+		return "", false, nil
+	}
+	key, ok := os.LookupEnv(providerCfg.APIKeyEnv)
+	if !ok {
+		return "", true, fmt.Errorf("environment variable %s is not set", providerCfg.APIKeyEnv)
+	}
+	return strings.TrimSpace(key), true, nil
+}
+
+// CommandKeySource runs api_key_cmd, such as a password manager, and reads the API key from its output.
+type CommandKeySource struct{}
+
+// Name identifies the source in error messages.
+func (CommandKeySource) Name() string {
+	return "api_key_cmd"
+}
+
+// Lookup runs the command and returns the first line of its output, like 'pass show' prints it.
+func (CommandKeySource) Lookup(ctx context.Context, providerType config.ProviderType, providerCfg config.ProviderConfig) (string, bool, error) {
+	if len(providerCfg.APIKeyCmd) == 0 {
+		return "", false, nil
+	}
+	output, err := runKeyCommand(ctx, providerCfg.APIKeyCmd)
+	if err != nil {
+		return "", true, err
+	}
+	key, _, _ := strings.Cut(output, "\n")
+	return strings.TrimSpace(key), true, nil
+}
+
+/*
+KeyringKeySource reads the API key from the OS keyring when api_key_keyring is set. Keys are
+stored under the service 'commitgen' with the provider name as account, and read through the
+'security' tool on macOS and the Secret Service's 'secret-tool' elsewhere.
+*/
+type KeyringKeySource struct{}
+
+// Name identifies the source in error messages.
+func (KeyringKeySource) Name() string {
+	return "the OS keyring"
+}
+
+// Lookup asks the keyring for the key of the provider.
+func (KeyringKeySource) Lookup(ctx context.Context, providerType config.ProviderType, providerCfg config.ProviderConfig) (string, bool, error) {
+	if !providerCfg.APIKeyKeyring {
+		return "", false, nil
+	}
+	args, err := keyringCommand(runtime.GOOS, providerType)
+	if err != nil {
+		return "", true, err
+	}
+	output, err := runKeyCommand(ctx, args)
+	if err != nil {
+		return "", true, err
+	}
+	return strings.TrimSpace(output), true, nil
+}
+
+// keyringCommand returns the command that prints the key of providerType from the keyring of goos.
+func keyringCommand(goos string, providerType config.ProviderType) ([]string, error) {
+	switch goos {
+	case "darwin":
+		return []string{"security", "find-generic-password", "-s", keyringService, "-a", string(providerType), "-w"}, nil
+	case "windows":
+		return nil, errors.New("the keyring is not supported on Windows, use api_key_env or api_key_cmd instead")
+	}
+	return []string{"secret-tool", "lookup", "service", keyringService, "account", string(providerType)}, nil
+}
+
+// runKeyCommand runs a command printing a secret and returns its output, including its stderr in errors.
+func runKeyCommand(ctx context.Context, args []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, keyCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Password managers may ask for a passphrase on the terminal.
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("command %q failed: %w: %s", args[0], err, message)
+		}
+		return "", fmt.Errorf("command %q failed: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
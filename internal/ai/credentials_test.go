@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	t.Setenv("COMMITGEN_TEST_KEY", "env-key\n")
+
+	testCases := []struct {
+		name        string
+		providerCfg config.ProviderConfig
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:        "literal key takes precedence",
+			providerCfg: config.ProviderConfig{APIKey: "literal-key", APIKeyEnv: "COMMITGEN_TEST_KEY"},
+			expected:    "literal-key",
+		},
+		{
+			name:        "environment variable",
+			providerCfg: config.ProviderConfig{APIKeyEnv: "COMMITGEN_TEST_KEY"},
+			expected:    "env-key",
+		},
+		{
+			name:        "unset environment variable",
+			providerCfg: config.ProviderConfig{APIKeyEnv: "COMMITGEN_TEST_MISSING_KEY"},
+			expectedErr: "COMMITGEN_TEST_MISSING_KEY is not set",
+		},
+		{
+			name:        "command prints the key on its first line",
+			providerCfg: config.ProviderConfig{APIKeyCmd: []string{"sh", "-c", `printf 'cmd-key\nlogin: me\n'`}},
+			expected:    "cmd-key",
+		},
+		{
+			name:        "failing command",
+			providerCfg: config.ProviderConfig{APIKeyCmd: []string{"sh", "-c", `echo 'store locked' >&2; exit 1`}},
+			expectedErr: "store locked",
+		},
+		{
+			name:        "command without output",
+			providerCfg: config.ProviderConfig{APIKeyCmd: []string{"true"}},
+			expectedErr: "is empty",
+		},
+		{
+			name:        "no key source",
+			providerCfg: config.ProviderConfig{},
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := setupTestConfig()
+			cfg.AI.Providers[config.OpenAI] = tc.providerCfg
+
+			err := resolveAPIKey(cfg, config.OpenAI)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveAPIKey failed: %v", err)
+			}
+			if key := cfg.AI.Providers[config.OpenAI].APIKey; key != tc.expected {
+				t.Errorf("expected key %q, got %q", tc.expected, key)
+			}
+		})
+	}
+}
+
+func TestKeyringCommand(t *testing.T) {
+	args, err := keyringCommand("darwin", config.Gemini)
+	if err != nil || !reflect.DeepEqual(args, []string{"security", "find-generic-password", "-s", "commitgen", "-a", "gemini", "-w"}) {
+		t.Errorf("unexpected macOS keyring command %q, %v", args, err)
+	}
+
+	args, err = keyringCommand("linux", config.Gemini)
+	if err != nil || !reflect.DeepEqual(args, []string{"secret-tool", "lookup", "service", "commitgen", "account", "gemini"}) {
+		t.Errorf("unexpected Secret Service keyring command %q, %v", args, err)
+	}
+
+	if _, err := keyringCommand("windows", config.Gemini); err == nil {
+		t.Error("expected an error for the unsupported Windows keyring, got nil")
+	}
+}
+
+func TestGetProvider_APIKeyEnv(t *testing.T) {
+	t.Setenv("COMMITGEN_TEST_OPENAI_KEY", "env-key")
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.OpenAI
+	cfg.AI.Providers[config.OpenAI] = config.ProviderConfig{Model: "gpt-test", APIKeyEnv: "COMMITGEN_TEST_OPENAI_KEY"}
+	cfg.SetupLocalProviderOverrides()
+
+	if _, err := GetProvider(cfg); err != nil {
+		t.Fatalf("expected the API key to be read from the environment, got %v", err)
+	}
+	if key := cfg.AI.Providers[config.OpenAI].APIKey; key != "env-key" {
+		t.Errorf("expected key %q, got %q", "env-key", key)
+	}
+}
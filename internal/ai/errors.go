@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrGenerationStopped is returned when the model stopped before producing a complete message.
+var ErrGenerationStopped = errors.New("AI generation stopped for reason")
+
+/*
+APIError describes a non-successful HTTP response returned by an AI provider.
+RetryAfter is set when the provider told the client how long to wait before retrying.
+*/
+type APIError struct {
+	Provider   config.ProviderType
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// ErrorKind classifies provider errors so callers can decide whether to fall back or give up.
+type ErrorKind int
+
+const (
+	ErrorKindUnknown ErrorKind = iota
+	ErrorKindAuth
+	ErrorKindRateLimit
+	ErrorKindServer
+	ErrorKindTimeout
+	ErrorKindNetwork
+	ErrorKindStopped
+	ErrorKindCanceled
+)
+
+// String returns a short human-readable name for the error kind.
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindAuth:
+		return "authentication"
+	case ErrorKindRateLimit:
+		return "rate limit"
+	case ErrorKindServer:
+		return "server error"
+	case ErrorKindTimeout:
+		return "timeout"
+	case ErrorKindNetwork:
+		return "network"
+	case ErrorKindStopped:
+		return "generation stopped"
+	case ErrorKindCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// ClassifyError determines the ErrorKind of an error returned by an LLMProvider.
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindUnknown
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindTimeout
+	}
+	if errors.Is(err, ErrGenerationStopped) {
+		return ErrorKindStopped
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ErrorKindAuth
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorKindRateLimit
+		case apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusGatewayTimeout:
+			return ErrorKindTimeout
+		case apiErr.StatusCode >= 500:
+			return ErrorKindServer
+		}
+		return ErrorKindUnknown
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorKindTimeout
+		}
+		return ErrorKindNetwork
+	}
+	return ErrorKindUnknown
+}
+
+/*
+newAPIError builds an APIError from an HTTP response and its body. It understands the common
+`{"error": {"message": "..."}}` and `{"error": "..."}` shapes and falls back to the raw
+body, or the HTTP status text if the body is empty.
+*/
+func newAPIError(provider config.ProviderType, resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    extractErrorMessage(resp.StatusCode, body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+/*
+parseRetryAfter parses a Retry-After header, which holds either a number of seconds or an
+HTTP date. It returns zero if the header is missing or invalid.
+*/
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// extractErrorMessage returns the most readable error message found in an HTTP error body.
+func extractErrorMessage(statusCode int, body []byte) string {
+	var structured struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &structured); err == nil && structured.Error.Message != "" {
+		return structured.Error.Message
+	}
+
+	var plain struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &plain); err == nil && plain.Error != "" {
+		return plain.Error
+	}
+
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return text
+	}
+	return http.StatusText(statusCode)
+}
+
+/*
+StoppedError is returned when the model stopped before producing a complete message. Response
+holds what was generated until then along with the tokens used, so that a message cut off by
+max_tokens can still be shown. It matches ErrGenerationStopped.
+*/
+type StoppedError struct {
+	Reason         FinishReason
+	ProviderReason string
+	Response       GenerateResponse
+}
+
+// Error describes the stop reason along with what the user can do about it.
+func (e *StoppedError) Error() string {
+	message := fmt.Sprintf("%v: %s", ErrGenerationStopped, e.ProviderReason)
+	switch e.Reason {
+	case FinishReasonMaxTokens:
+		return message + " (the output reached max_tokens before the message was complete; raise max_tokens, or lower the thinking budget of thinking models)"
+	case FinishReasonSafety:
+		return message + " (the response was blocked by the provider's safety filters; check the staged diff for secrets or offensive content, or unstage the affected files)"
+	case FinishReasonRecitation:
+		return message + " (the response was blocked for reciting existing material such as licensed code; try again with a higher temperature, or unstage vendored and third-party files)"
+	}
+	return message
+}
+
+func (e *StoppedError) Unwrap() error {
+	return ErrGenerationStopped
+}
+
+// newStoppedError reports that generation ended early, keeping the provider-specific reason for the message.
+func newStoppedError(reason FinishReason, providerReason string) *StoppedError {
+	return &StoppedError{
+		Reason:         reason,
+		ProviderReason: providerReason,
+	}
+}
@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	execInputText = "text"
+	execInputJSON = "json"
+)
+
+/*
+ExecProvider implements the LLMProvider interface by running an external command.
+The prompt is written to the command's stdin and the commit message is read from its stdout,
+which lets any script or internal CLI act as an LLM backend.
+*/
+type ExecProvider struct {
+	cfg *config.Config
+}
+
+/*
+execRequest is the JSON document written to the command's stdin when input_format is 'json'.
+It carries both the rendered prompt and the raw prompt data so scripts can use either.
+*/
+type execRequest struct {
+	System                string            `json:"system,omitempty"`
+	Prompt                string            `json:"prompt"`
+	StagedDiff            string            `json:"staged_diff"`
+	CommitTypes           map[string]string `json:"commit_types"`
+	DefaultCommitType     string            `json:"default_commit_type"`
+	ForcedCommitType      string            `json:"forced_commit_type,omitempty"`
+	ExistingCommitMessage string            `json:"existing_commit_message,omitempty"`
+	Scopes                []string          `json:"scopes,omitempty"`
+	Language              string            `json:"language,omitempty"`
+	RepoName              string            `json:"repo_name,omitempty"`
+	Branch                string            `json:"branch,omitempty"`
+	Upstream              string            `json:"upstream,omitempty"`
+	RecentCommits         []string          `json:"recent_commits,omitempty"`
+	StagedFiles           []string          `json:"staged_files,omitempty"`
+	DiffStat              string            `json:"diff_stat,omitempty"`
+	StyleGuidelines       []string          `json:"style_guidelines,omitempty"`
+	StyleExamples         []string          `json:"style_examples,omitempty"`
+	Model                 string            `json:"model,omitempty"`
+	MaxTokens             *int32            `json:"max_tokens,omitempty"`
+	Temperature           *float32          `json:"temperature,omitempty"`
+	JSONOutput            bool              `json:"json_output,omitempty"`
+}
+
+// NewExecProvider creates and initializes a new ExecProvider instance with the given configuration.
+func NewExecProvider(cfg *config.Config) (*ExecProvider, error) {
+	providerCfg := cfg.AI.Providers[config.Exec]
+	if len(providerCfg.Command) == 0 {
+		return nil, fmt.Errorf("missing command for provider %s", config.Exec)
+	}
+
+	switch providerCfg.InputFormat {
+	case "", execInputText, execInputJSON:
+	default:
+		return nil, fmt.Errorf("unsupported input_format for provider %s: %q", config.Exec, providerCfg.InputFormat)
+	}
+
+	provider := &ExecProvider{
+		cfg: cfg,
+	}
+	return provider, nil
+}
+
+// Capabilities reports the optional features supported by the exec provider.
+func (p ExecProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true}
+}
+
+// buildInput returns the bytes written to the command's stdin according to the configured input format.
+func (p ExecProvider) buildInput(req GenerateRequest) ([]byte, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return nil, err
+	}
+
+	providerCfg := p.cfg.AI.Providers[config.Exec]
+	if providerCfg.InputFormat != execInputJSON {
+		return []byte(joinPrompts(system, prompt)), nil
+	}
+
+	stagedFiles := make([]string, len(req.PromptData.StagedFiles))
+	for i, file := range req.PromptData.StagedFiles {
+		stagedFiles[i] = file.String()
+	}
+
+	opts := resolveOptions(providerCfg, req.Options)
+	return json.Marshal(execRequest{
+		System:                system,
+		Prompt:                prompt,
+		StagedDiff:            req.PromptData.StagedDiff,
+		CommitTypes:           req.PromptData.CommitTypes,
+		DefaultCommitType:     req.PromptData.DefaultCommitType,
+		ForcedCommitType:      req.PromptData.ForcedCommitType,
+		ExistingCommitMessage: req.PromptData.ExistingCommitMessage,
+		Scopes:                req.PromptData.Scopes,
+		Language:              req.PromptData.Language,
+		RepoName:              req.PromptData.RepoName,
+		Branch:                req.PromptData.Branch,
+		Upstream:              req.PromptData.Upstream,
+		RecentCommits:         req.PromptData.RecentCommits,
+		StagedFiles:           stagedFiles,
+		DiffStat:              req.PromptData.DiffStat,
+		StyleGuidelines:       req.PromptData.Style.Guidelines(),
+		StyleExamples:         req.PromptData.Style.Examples,
+		Model:                 providerCfg.Model,
+		MaxTokens:             opts.MaxTokens,
+		Temperature:           opts.Temperature,
+		JSONOutput:            opts.JSON,
+	})
+}
+
+/*
+Generate runs the configured command, feeds it the prompt on stdin and returns its trimmed stdout.
+A non-zero exit status, a timeout or an empty output are reported as errors.
+*/
+func (p ExecProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	input, err := p.buildInput(req)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	providerCfg := p.cfg.AI.Providers[config.Exec]
+	name := providerCfg.Command[0]
+	cmd := exec.CommandContext(ctx, name, providerCfg.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	// Don't wait forever on grandchildren that keep stdout open after the command was killed.
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	if req.Stream != nil {
+		cmd.Stdout = &streamWriter{stream: req.Stream, buffer: &stdout}
+	}
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return GenerateResponse{}, fmt.Errorf("command %q timed out: %w", name, ctx.Err())
+		}
+		if ctx.Err() != nil {
+			return GenerateResponse{}, fmt.Errorf("command %q was cancelled: %w", name, ctx.Err())
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return GenerateResponse{}, fmt.Errorf(
+				"command %q exited with status %d: %s",
+				name, exitErr.ExitCode(), strings.TrimSpace(stderr.String()),
+			)
+		}
+		return GenerateResponse{}, fmt.Errorf("could not run command %q: %w", name, err)
+	}
+
+	message := strings.TrimSpace(stdout.String())
+	if message == "" {
+		return GenerateResponse{}, fmt.Errorf("command %q produced no output", name)
+	}
+
+	return GenerateResponse{
+		Message:      message,
+		FinishReason: FinishReasonStop,
+		Provider:     config.Exec,
+		Model:        providerCfg.Model,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+execTestConfig returns the [ai.providers.exec] table that runs the given shell script
+through 'sh -c' with the given input format and timeout.
+*/
+func execTestConfig(script, inputFormat string, timeout time.Duration) config.ProviderConfig {
+	return config.ProviderConfig{
+		Model:       "script",
+		Command:     []string{"sh", "-c", script},
+		InputFormat: inputFormat,
+		Timeout:     config.Duration(timeout),
+	}
+}
+
+func TestExecGenerate_TextInput(t *testing.T) {
+	// The fake backend echoes a message only if the prompt contains the staged diff.
+	script := `grep -q 'fmt.Println' && printf 'feat: Print greeting\n\n- Add main\n'`
+	provider := setupTestProvider(t, config.Exec, execTestConfig(script, "", 0), GetProvider, nil)
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Print greeting\n\n- Add main" {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+}
+
+func TestExecGenerate_JSONInput(t *testing.T) {
+	script := `input=$(cat)
+echo "$input" | grep -q '"default_commit_type":"refactor"' &&
+echo "$input" | grep -q '"model":"script"' &&
+echo 'refactor: Use JSON'`
+	provider := setupTestProvider(t, config.Exec, execTestConfig(script, "json", 0), GetProvider, nil)
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "refactor: Use JSON" {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+}
+
+func TestExecGenerate_NonZeroExit(t *testing.T) {
+	provider := setupTestProvider(t, config.Exec, execTestConfig(`echo 'quota exceeded' >&2; exit 3`, "", 0), GetProvider, nil)
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil {
+		t.Fatal("expected an error for a failing command, got nil")
+	}
+	if !strings.Contains(err.Error(), "status 3") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the exit status and stderr in the error, got %v", err)
+	}
+}
+
+func TestExecGenerate_Timeout(t *testing.T) {
+	provider := setupTestProvider(t, config.Exec, execTestConfig(`exec sleep 5`, "", 50*time.Millisecond), GetProvider, nil)
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestExecGenerate_EmptyOutput(t *testing.T) {
+	provider := setupTestProvider(t, config.Exec, execTestConfig(`cat > /dev/null`, "", 0), GetProvider, nil)
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err == nil {
+		t.Fatal("expected an error for empty output, got nil")
+	}
+}
+
+func TestNewExecProvider_InvalidConfig(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.Exec] = config.ProviderConfig{}
+	if _, err := NewExecProvider(cfg); err == nil {
+		t.Error("expected an error for a missing command, got nil")
+	}
+
+	cfg.AI.Providers[config.Exec] = config.ProviderConfig{Command: []string{"cat"}, InputFormat: "yaml"}
+	if _, err := NewExecProvider(cfg); err == nil {
+		t.Error("expected an error for an unsupported input format, got nil")
+	}
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"fmt"
+)
+
+/*
+FallbackProvider implements the LLMProvider interface by trying an ordered list of providers.
+When a provider fails with an error worth falling back on (see shouldFallback), the request is
+transparently retried with the next provider in the chain.
+*/
+type FallbackProvider struct {
+	providers []LLMProvider
+	names     []config.ProviderType
+}
+
+// FallbackAttempt records a provider that failed before another one produced the message.
+type FallbackAttempt struct {
+	Provider config.ProviderType
+	Err      error
+}
+
+// NewFallbackProvider creates a FallbackProvider from providers and their matching names, in priority order.
+func NewFallbackProvider(providers []LLMProvider, names []config.ProviderType) (*FallbackProvider, error) {
+	if len(providers) == 0 || len(providers) != len(names) {
+		return nil, fmt.Errorf("a fallback chain needs one name per provider and at least one provider")
+	}
+
+	provider := &FallbackProvider{
+		providers: providers,
+		names:     names,
+	}
+	return provider, nil
+}
+
+// Capabilities reports the features supported by every provider of the chain.
+func (p FallbackProvider) Capabilities() Capabilities {
+	capabilities := p.providers[0].Capabilities()
+	for _, provider := range p.providers[1:] {
+		other := provider.Capabilities()
+		capabilities.Streaming = capabilities.Streaming && other.Streaming
+		capabilities.JSONMode = capabilities.JSONMode && other.JSONMode
+		capabilities.TokenCounting = capabilities.TokenCounting && other.TokenCounting
+	}
+	return capabilities
+}
+
+/*
+Generate asks each provider in turn until one of them returns a message. The failed attempts
+are attached to the response so the caller can report which provider was finally used.
+*/
+func (p FallbackProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	var attempts []FallbackAttempt
+	for i, provider := range p.providers {
+		resp, err := provider.Generate(ctx, req)
+		if err == nil {
+			resp.Fallbacks = append(attempts, resp.Fallbacks...)
+			return resp, nil
+		}
+
+		attempts = append(attempts, FallbackAttempt{Provider: p.names[i], Err: err})
+		if ctx.Err() != nil || !shouldFallback(err) || i == len(p.providers)-1 {
+			return GenerateResponse{}, p.chainError(attempts)
+		}
+	}
+	return GenerateResponse{}, p.chainError(attempts)
+}
+
+// chainError wraps the last error of the chain and mentions every provider that was tried.
+func (p FallbackProvider) chainError(attempts []FallbackAttempt) error {
+	last := attempts[len(attempts)-1]
+	if len(attempts) == 1 {
+		return last.Err
+	}
+
+	tried := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		tried[i] = string(attempt.Provider)
+	}
+	return fmt.Errorf("all fallback providers failed (tried %v): %w", tried, last.Err)
+}
+
+/*
+shouldFallback reports whether the next provider of a chain should be tried after err.
+Authentication failures, rate limits, server errors, timeouts, unreachable hosts and
+stopped generations are all specific to one provider, so another one may still succeed.
+*/
+func shouldFallback(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorKindAuth, ErrorKindRateLimit, ErrorKindServer, ErrorKindTimeout, ErrorKindNetwork, ErrorKindStopped:
+		return true
+	}
+	return false
+}
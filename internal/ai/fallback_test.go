@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubProvider is an LLMProvider returning a fixed response or error, counting its calls.
+type stubProvider struct {
+	resp  GenerateResponse
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	p.calls++
+	return p.resp, p.err
+}
+
+func (p *stubProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true}
+}
+
+func TestFallbackGenerate(t *testing.T) {
+	testCases := []struct {
+		name             string
+		primaryErr       error
+		expectedProvider config.ProviderType
+		expectFallback   bool
+	}{
+		{
+			name:             "primary succeeds",
+			primaryErr:       nil,
+			expectedProvider: config.Gemini,
+		},
+		{
+			name:             "rate limit falls back",
+			primaryErr:       &APIError{Provider: config.Gemini, StatusCode: http.StatusTooManyRequests},
+			expectedProvider: config.OpenAI,
+			expectFallback:   true,
+		},
+		{
+			name:             "auth error falls back",
+			primaryErr:       &APIError{Provider: config.Gemini, StatusCode: http.StatusUnauthorized},
+			expectedProvider: config.OpenAI,
+			expectFallback:   true,
+		},
+		{
+			name:             "stopped generation falls back",
+			primaryErr:       newStoppedError(FinishReasonSafety, "SAFETY"),
+			expectedProvider: config.OpenAI,
+			expectFallback:   true,
+		},
+		{
+			name:             "timeout falls back",
+			primaryErr:       context.DeadlineExceeded,
+			expectedProvider: config.OpenAI,
+			expectFallback:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			primary := &stubProvider{resp: GenerateResponse{Message: "primary", Provider: config.Gemini}, err: tc.primaryErr}
+			secondary := &stubProvider{resp: GenerateResponse{Message: "secondary", Provider: config.OpenAI}}
+
+			provider, err := NewFallbackProvider(
+				[]LLMProvider{primary, secondary},
+				[]config.ProviderType{config.Gemini, config.OpenAI},
+			)
+			if err != nil {
+				t.Fatalf("NewFallbackProvider failed: %v", err)
+			}
+
+			resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Provider != tc.expectedProvider {
+				t.Errorf("expected provider %q, got %q", tc.expectedProvider, resp.Provider)
+			}
+			if tc.expectFallback && (len(resp.Fallbacks) != 1 || resp.Fallbacks[0].Provider != config.Gemini) {
+				t.Errorf("expected the failed gemini attempt to be recorded, got %+v", resp.Fallbacks)
+			}
+			if !tc.expectFallback && secondary.calls != 0 {
+				t.Errorf("expected the secondary provider not to be called")
+			}
+		})
+	}
+}
+
+func TestFallbackGenerate_NonFallbackError(t *testing.T) {
+	badRequest := &APIError{Provider: config.Gemini, StatusCode: http.StatusBadRequest, Message: "invalid model"}
+	primary := &stubProvider{err: badRequest}
+	secondary := &stubProvider{resp: GenerateResponse{Message: "secondary"}}
+
+	provider, _ := NewFallbackProvider(
+		[]LLMProvider{primary, secondary},
+		[]config.ProviderType{config.Gemini, config.OpenAI},
+	)
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if !errors.Is(err, badRequest) {
+		t.Errorf("expected the original error, got %v", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("expected the secondary provider not to be called for a bad request")
+	}
+}
+
+func TestFallbackGenerate_AllFail(t *testing.T) {
+	primary := &stubProvider{err: &APIError{Provider: config.Gemini, StatusCode: http.StatusServiceUnavailable}}
+	secondary := &stubProvider{err: &APIError{Provider: config.OpenAI, StatusCode: http.StatusTooManyRequests}}
+
+	provider, _ := NewFallbackProvider(
+		[]LLMProvider{primary, secondary},
+		[]config.ProviderType{config.Gemini, config.OpenAI},
+	)
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if ClassifyError(err) != ErrorKindRateLimit {
+		t.Errorf("expected the last provider's rate limit error, got %v", err)
+	}
+}
+
+func TestGetProvider_FallbackChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"fix: Fall back"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.Exec
+	cfg.AI.FallbackProviders = []config.ProviderType{config.OpenAICompatible}
+	cfg.AI.Providers[config.Exec] = config.ProviderConfig{Command: []string{"sh", "-c", "exec sleep 5"}, Timeout: 1}
+	cfg.AI.Providers[config.OpenAICompatible] = config.ProviderConfig{Model: "local", BaseURL: server.URL}
+	cfg.SetupLocalProviderOverrides()
+
+	provider, err := GetProvider(cfg)
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Provider != config.OpenAICompatible || resp.Message != "fix: Fall back" {
+		t.Errorf("expected the openai_compatible fallback to answer, got %+v", resp)
+	}
+}
+
+func TestGetProvider_UnusableProviders(t *testing.T) {
+	testCases := []struct {
+		name             string
+		defaultProvider  config.ProviderType
+		fallbacks        []config.ProviderType
+		expectedProvider config.ProviderType
+		expectedSkipped  int
+		expectErr        bool
+	}{
+		{"Unusable fallback", config.Mock, []config.ProviderType{config.OpenAI}, config.Mock, 1, false},
+		{"Unusable default provider", config.OpenAI, []config.ProviderType{config.Anthropic, config.Mock}, config.Mock, 2, false},
+		{"No usable provider", config.OpenAI, []config.ProviderType{config.Anthropic}, "", 2, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := setupTestConfig()
+			cfg.AI.DefaultProvider = tc.defaultProvider
+			cfg.AI.FallbackProviders = tc.fallbacks
+			// Neither OpenAI nor Anthropic have an API key.
+			cfg.AI.Providers[config.OpenAI] = config.ProviderConfig{Model: "gpt-test"}
+			cfg.AI.Providers[config.Anthropic] = config.ProviderConfig{Model: "claude-test"}
+			cfg.AI.Providers[config.Mock] = config.ProviderConfig{Responses: []string{"fix: Use the mock"}}
+
+			provider, err := GetProvider(cfg)
+			if tc.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "missing API key for provider openai") {
+					t.Errorf("expected the error of the default provider, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetProvider failed: %v", err)
+			}
+
+			resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Provider != tc.expectedProvider {
+				t.Errorf("expected provider %s to answer, got %s", tc.expectedProvider, resp.Provider)
+			}
+			if len(cfg.Warnings) != tc.expectedSkipped || !strings.Contains(cfg.Warnings[0], "Skipping provider") {
+				t.Errorf("expected a warning for every skipped provider, got %q", cfg.Warnings)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{"forbidden", &APIError{StatusCode: http.StatusForbidden}, ErrorKindAuth},
+		{"too many requests", &APIError{StatusCode: http.StatusTooManyRequests}, ErrorKindRateLimit},
+		{"service unavailable", &APIError{StatusCode: http.StatusServiceUnavailable}, ErrorKindServer},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, ErrorKindUnknown},
+		{"canceled", context.Canceled, ErrorKindCanceled},
+		{"stopped", newStoppedError(FinishReasonMaxTokens, "length"), ErrorKindStopped},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if kind := ClassifyError(tc.err); kind != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, kind)
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package ai
 
 import (
 	"CommitGen/internal/config"
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"text/template"
+	"net/http"
+	"strings"
+	"time"
 
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
 	"google.golang.org/genai"
 )
 
@@ -16,14 +21,20 @@ type GeminiProvider struct {
 	client *genai.Client
 }
 
+const (
+	geminiBackendAPI      = "gemini_api"
+	geminiBackendVertexAI = "vertex_ai"
+	googleCloudScope      = "https://www.googleapis.com/auth/cloud-platform"
+)
+
 // NewGeminiProvider creates and initializes a new GeminiProvider instance with the given configuration.
 func NewGeminiProvider(cfg *config.Config) (*GeminiProvider, error) {
-	providerCfg := cfg.AI.Providers[config.Gemini]
+	clientCfg, err := newGeminiClientConfig(cfg.AI.Providers[config.Gemini])
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := genai.NewClient(context.TODO(), &genai.ClientConfig{
-		APIKey:  providerCfg.APIKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	client, err := genai.NewClient(context.TODO(), clientCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -36,73 +47,349 @@ func NewGeminiProvider(cfg *config.Config) (*GeminiProvider, error) {
 }
 
 /*
-buildPrompt constructs the prompt string for the Gemini LLM by executing the configured
-prompt template with the relevant data.
+newGeminiClientConfig translates the Gemini provider settings into a genai.ClientConfig.
+The 'gemini_api' backend authenticates with an API key, while 'vertex_ai' uses the project,
+location and either a service-account file or Application Default Credentials.
 */
-func (p GeminiProvider) buildPrompt(stagedDiff, existingCommitMessage string) (string, error) {
-	data := PromptData{
-		StagedDiff:            stagedDiff,
-		CommitTypes:           p.cfg.Prompt.CommitTypes,
-		DefaultCommitType:     p.cfg.DefaultType,
-		ForcedCommitType:      p.cfg.ForcedCommitType,
-		ExistingCommitMessage: existingCommitMessage,
+func newGeminiClientConfig(providerCfg config.ProviderConfig) (*genai.ClientConfig, error) {
+	transport, err := newHTTPTransport(config.Gemini, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch providerCfg.Backend {
+	case "", geminiBackendAPI:
+		return &genai.ClientConfig{
+			APIKey:     providerCfg.APIKey,
+			Backend:    genai.BackendGeminiAPI,
+			HTTPClient: &http.Client{Transport: transport},
+		}, nil
+
+	case geminiBackendVertexAI:
+		if providerCfg.Project == "" || providerCfg.Location == "" {
+			return nil, fmt.Errorf("the %s backend requires both project and location to be set", geminiBackendVertexAI)
+		}
+
+		clientCfg := &genai.ClientConfig{
+			Backend:  genai.BackendVertexAI,
+			Project:  providerCfg.Project,
+			Location: providerCfg.Location,
+		}
+
+		// Without a credentials file or network settings genai falls back to Application Default Credentials itself.
+		if providerCfg.CredentialsFile != "" || hasNetworkSettings(providerCfg) {
+			// Tokens are fetched through the same proxy and TLS settings as the API calls.
+			creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+				Scopes:          []string{googleCloudScope},
+				CredentialsFile: providerCfg.CredentialsFile,
+				Client:          &http.Client{Transport: transport},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("could not load credentials for the %s backend: %w", geminiBackendVertexAI, err)
+			}
+			clientCfg.Credentials = creds
+		}
+
+		// genai only authenticates the clients it creates, so a custom client must do it itself.
+		if hasNetworkSettings(providerCfg) {
+			client, err := newVertexHTTPClient(clientCfg.Credentials, transport)
+			if err != nil {
+				return nil, err
+			}
+			clientCfg.HTTPClient = client
+		}
+		return clientCfg, nil
 	}
+	return nil, fmt.Errorf("unsupported Gemini backend: %q", providerCfg.Backend)
+}
 
-	tmpl, err := template.New("prompt").Parse(p.cfg.Prompt.Template)
+// newVertexHTTPClient creates an HTTP client that authenticates Vertex AI requests with creds over transport.
+func newVertexHTTPClient(creds *auth.Credentials, transport http.RoundTripper) (*http.Client, error) {
+	headers := http.Header{}
+	quotaProjectID, err := creds.QuotaProjectID(context.TODO())
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("could not get the quota project of the credentials: %w", err)
+	}
+	if quotaProjectID != "" {
+		headers.Set("X-Goog-User-Project", quotaProjectID)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", err
+	client, err := httptransport.NewClient(&httptransport.Options{
+		Credentials:      creds,
+		Headers:          headers,
+		BaseRoundTripper: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create the %s HTTP client: %w", geminiBackendVertexAI, err)
 	}
-	return buf.String(), nil
+	return client, nil
+}
+
+// Capabilities reports the optional features supported by the Gemini provider.
+func (p GeminiProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, JSONMode: true, TokenCounting: true, MultipleCandidates: true}
+}
+
+// CountTokens returns the number of tokens the configured Gemini model uses for the prompt.
+func (p GeminiProvider) CountTokens(ctx context.Context, prompt string) (int32, error) {
+	model := p.cfg.AI.Providers[config.Gemini].Model
+	result, err := p.client.Models.CountTokens(ctx, model, genai.Text(prompt), nil)
+	if err != nil {
+		return 0, convertGeminiError(err)
+	}
+	return result.TotalTokens, nil
 }
 
 /*
 Generate sends the constructed prompt to the Gemini LLM and returns the generated commit message.
 It handles potential errors and empty responses from the AI provider.
 */
-func (p GeminiProvider) Generate(ctx context.Context, stagedDiff, existingCommitMessage string) (string, error) {
-	prompt, err := p.buildPrompt(stagedDiff, existingCommitMessage)
+func (p GeminiProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
 	if err != nil {
-		return "", err
+		return GenerateResponse{}, err
 	}
 
 	providerCfg := p.cfg.AI.Providers[config.Gemini]
-	result, err := p.client.Models.GenerateContent(
-		ctx,
-		providerCfg.Model,
-		genai.Text(prompt),
-		&genai.GenerateContentConfig{
-			Temperature:     providerCfg.Temperature,
-			MaxOutputTokens: *providerCfg.MaxTokens,
-		})
+	opts := resolveOptions(providerCfg, req.Options)
 
+	generateCfg := &genai.GenerateContentConfig{Temperature: opts.Temperature}
+	if system != "" {
+		generateCfg.SystemInstruction = genai.NewContentFromText(system, genai.RoleUser)
+	}
+	if opts.MaxTokens != nil {
+		generateCfg.MaxOutputTokens = *opts.MaxTokens
+	}
+	if opts.Candidates > 1 {
+		generateCfg.CandidateCount = int32(opts.Candidates)
+	}
+	if opts.JSON {
+		generateCfg.ResponseMIMEType = "application/json"
+		generateCfg.ResponseSchema = geminiCommitMessageSchema()
+	}
+	if thinking := providerCfg.Thinking; thinking != nil {
+		generateCfg.ThinkingConfig = &genai.ThinkingConfig{
+			ThinkingBudget:  thinking.Budget,
+			IncludeThoughts: thinking.IncludeThoughts,
+		}
+	}
+
+	var result *genai.GenerateContentResponse
+	if req.Stream != nil {
+		result, err = p.generateStream(ctx, providerCfg.Model, genai.Text(prompt), generateCfg, req.Stream)
+	} else {
+		result, err = p.client.Models.GenerateContent(ctx, providerCfg.Model, genai.Text(prompt), generateCfg)
+	}
 	if err != nil {
-		return "", err
+		return GenerateResponse{}, convertGeminiError(err)
 	}
 
 	if result == nil || len(result.Candidates) == 0 {
-		return "", fmt.Errorf("received an empty response from the AI provider")
+		return GenerateResponse{}, fmt.Errorf("received an empty response from the AI provider")
 	}
 
-	// Check the finish reason. If it's not 'STOP', the model was likely blocked.
-	candidate := result.Candidates[0]
-	if candidate.FinishReason != genai.FinishReasonStop {
-		return "", fmt.Errorf("AI generation stopped for reason: %s", candidate.FinishReason)
+	response := GenerateResponse{
+		Provider: config.Gemini,
+		Model:    providerCfg.Model,
+	}
+	if usage := result.UsageMetadata; usage != nil {
+		response.Usage = Usage{
+			PromptTokens:   usage.PromptTokenCount,
+			OutputTokens:   usage.CandidatesTokenCount,
+			ThinkingTokens: usage.ThoughtsTokenCount,
+			TotalTokens:    usage.TotalTokenCount,
+		}
 	}
 
-	var responseBuilder bytes.Buffer
-	if candidate.Content != nil {
-		for _, part := range candidate.Content.Parts {
-			responseBuilder.WriteString(part.Text)
+	// Check the finish reasons. Candidates that did not 'STOP' were likely blocked or truncated.
+	var messages []string
+	var stopped *StoppedError
+	for _, candidate := range result.Candidates {
+		text, thoughts := geminiCandidateText(candidate)
+		if response.Thoughts == "" {
+			response.Thoughts = thoughts
+		}
+
+		if candidate.FinishReason != genai.FinishReasonStop {
+			if stopped == nil {
+				stopped = newStoppedError(geminiFinishReason(candidate.FinishReason), string(candidate.FinishReason))
+				stopped.Response = response
+				stopped.Response.Message = text
+				stopped.Response.FinishReason = stopped.Reason
+			}
+			continue
+		}
+		if text != "" {
+			messages = append(messages, text)
 		}
 	}
 
-	if responseBuilder.Len() == 0 {
-		return "", fmt.Errorf("AI returned a candidate with zero parts")
+	if len(messages) == 0 {
+		if stopped != nil {
+			return GenerateResponse{}, stopped
+		}
+		return GenerateResponse{}, fmt.Errorf("AI returned a candidate with zero parts")
+	}
+
+	response.Message = messages[0]
+	response.FinishReason = FinishReasonStop
+	if len(messages) > 1 {
+		response.Candidates = messages
+	}
+	return response, nil
+}
+
+// geminiCandidateText returns the text of a candidate, separated from the thoughts it includes.
+func geminiCandidateText(candidate *genai.Candidate) (text, thoughts string) {
+	if candidate.Content == nil {
+		return "", ""
+	}
+
+	var textBuilder, thoughtsBuilder strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughtsBuilder.WriteString(part.Text)
+		} else {
+			textBuilder.WriteString(part.Text)
+		}
+	}
+	return textBuilder.String(), thoughtsBuilder.String()
+}
+
+/*
+generateStream calls GenerateContentStream, reports the text of the first candidate received so
+far to stream after every chunk, and merges the chunks into a single response. Thoughts are
+kept apart from the text and never streamed.
+*/
+func (p GeminiProvider) generateStream(
+	ctx context.Context,
+	model string,
+	contents []*genai.Content,
+	generateCfg *genai.GenerateContentConfig,
+	stream func(partial string),
+) (*genai.GenerateContentResponse, error) {
+	var texts, thoughts []*strings.Builder
+	var finishReasons []genai.FinishReason
+	merged := &genai.GenerateContentResponse{}
+
+	for chunk, err := range p.client.Models.GenerateContentStream(ctx, model, contents, generateCfg) {
+		if err != nil {
+			return nil, err
+		}
+		if chunk.UsageMetadata != nil {
+			merged.UsageMetadata = chunk.UsageMetadata
+		}
+
+		for _, candidate := range chunk.Candidates {
+			index := int(candidate.Index)
+			for len(texts) <= index {
+				texts = append(texts, &strings.Builder{})
+				thoughts = append(thoughts, &strings.Builder{})
+				finishReasons = append(finishReasons, "")
+			}
+
+			if candidate.FinishReason != "" {
+				finishReasons[index] = candidate.FinishReason
+			}
+			text, thought := geminiCandidateText(candidate)
+			thoughts[index].WriteString(thought)
+			if text != "" {
+				texts[index].WriteString(text)
+				if index == 0 {
+					stream(texts[0].String())
+				}
+			}
+		}
+	}
+
+	for i := range texts {
+		if finishReasons[i] == "" && texts[i].Len() == 0 {
+			continue
+		}
+
+		parts := []*genai.Part{genai.NewPartFromText(texts[i].String())}
+		if thoughts[i].Len() > 0 {
+			parts = append([]*genai.Part{{Text: thoughts[i].String(), Thought: true}}, parts...)
+		}
+		merged.Candidates = append(merged.Candidates, &genai.Candidate{
+			Content:      genai.NewContentFromParts(parts, genai.RoleModel),
+			FinishReason: finishReasons[i],
+			Index:        int32(i),
+		})
+	}
+	return merged, nil
+}
+
+// geminiCommitMessageSchema returns the schema of CommitMessage in the form expected by Gemini's ResponseSchema.
+func geminiCommitMessageSchema() *genai.Schema {
+	stringSchema := &genai.Schema{Type: genai.TypeString}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"type":     {Type: genai.TypeString, Description: "The conventional commit type, e.g. 'feat' or 'fix'."},
+			"scope":    {Type: genai.TypeString, Description: "The optional scope of the change, or an empty string."},
+			"subject":  {Type: genai.TypeString, Description: "A short imperative summary starting with a capital letter."},
+			"body":     {Type: genai.TypeArray, Items: stringSchema, Description: "Bullet points explaining the change, without leading dashes."},
+			"breaking": {Type: genai.TypeBoolean, Description: "Whether the change breaks backwards compatibility."},
+			"footers": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type:       genai.TypeObject,
+					Properties: map[string]*genai.Schema{"token": stringSchema, "value": stringSchema},
+					Required:   []string{"token", "value"},
+				},
+				Description: "Git trailers such as 'Refs' or 'BREAKING CHANGE'.",
+			},
+		},
+		Required:         []string{"type", "scope", "subject", "body", "breaking", "footers"},
+		PropertyOrdering: []string{"type", "scope", "subject", "body", "breaking", "footers"},
+	}
+}
+
+// convertGeminiError turns a genai.APIError into an *APIError so it can be classified like other providers.
+func convertGeminiError(err error) error {
+	var genaiErr genai.APIError
+	if !errors.As(err, &genaiErr) {
+		return err
+	}
+	return &APIError{
+		Provider:   config.Gemini,
+		StatusCode: genaiErr.Code,
+		Message:    genaiErr.Message,
+		RetryAfter: geminiRetryDelay(genaiErr.Details),
+	}
+}
+
+/*
+geminiRetryDelay extracts the delay suggested by a google.rpc.RetryInfo entry of an error's
+details, which Gemini attaches to most 429 responses. It returns zero if none is present.
+*/
+func geminiRetryDelay(details []map[string]any) time.Duration {
+	for _, detail := range details {
+		if detail["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if parsed, err := time.ParseDuration(delay); err == nil {
+				return parsed
+			}
+		}
+	}
+	return 0
+}
+
+// geminiFinishReason maps a Gemini finish reason onto the provider-independent FinishReason.
+func geminiFinishReason(reason genai.FinishReason) FinishReason {
+	switch reason {
+	case genai.FinishReasonStop:
+		return FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return FinishReasonMaxTokens
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII:
+		return FinishReasonSafety
+	case genai.FinishReasonRecitation:
+		return FinishReasonRecitation
 	}
-	return responseBuilder.String(), nil
+	return FinishReasonOther
 }
@@ -2,10 +2,14 @@ package ai
 
 import (
 	"CommitGen/internal/config"
+	"CommitGen/internal/git"
 	"context"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"google.golang.org/genai"
 )
 
 const stagedDiff = "diff --git a/file.go b/file.go\nindex abcdef1..2345678 100644\n--- a/file.go\n+++ b/file.go\n@@ -1,1 +1,2 @@\n+func main() {\n+  fmt.Println(\"Hello\")\n}\n"
@@ -17,16 +21,23 @@ func setupTestConfig() *config.Config {
 		APIKey: "test-api-key",
 		Model:  "gemini-pro",
 	}
+	// Responses must come from the test servers, not from earlier runs.
+	cfg.AI.Cache.Enabled = false
+	cfg.AI.Usage.Enabled = false
 	return cfg
 }
 
+// newTestRequest builds a GenerateRequest for the given staged diff using the test config.
+func newTestRequest(stagedDiff string) GenerateRequest {
+	return GenerateRequest{PromptData: NewPromptData(setupTestConfig(), stagedDiff, "")}
+}
+
 func TestBuildPrompt_Basic(t *testing.T) {
 	cfg := setupTestConfig()
-	provider := GeminiProvider{cfg: cfg}
 
-	prompt, err := provider.buildPrompt(stagedDiff, "")
+	prompt, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
 	if err != nil {
-		t.Fatalf("buildPrompt failed: %v", err)
+		t.Fatalf("BuildPrompt failed: %v", err)
 	}
 
 	if !strings.Contains(prompt, stagedDiff) {
@@ -45,11 +56,10 @@ func TestBuildPrompt_Basic(t *testing.T) {
 func TestBuildPrompt_ForcedCommitType(t *testing.T) {
 	cfg := setupTestConfig()
 	cfg.ForcedCommitType = "feat"
-	provider := GeminiProvider{cfg: cfg}
 
-	prompt, err := provider.buildPrompt(stagedDiff, "")
+	prompt, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
 	if err != nil {
-		t.Fatalf("buildPrompt failed: %v", err)
+		t.Fatalf("BuildPrompt failed: %v", err)
 	}
 
 	if !strings.Contains(prompt, "- You MUST use the commit type: feat") {
@@ -67,11 +77,10 @@ func TestBuildPrompt_CustomCommitTypes(t *testing.T) {
 		"custom": "A custom change",
 		"new":    "A new entry",
 	}
-	provider := GeminiProvider{cfg: cfg}
 
-	prompt, err := provider.buildPrompt(stagedDiff, "")
+	prompt, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
 	if err != nil {
-		t.Fatalf("buildPrompt failed: %v", err)
+		t.Fatalf("BuildPrompt failed: %v", err)
 	}
 
 	if !strings.Contains(prompt, "- custom: A custom change") {
@@ -84,25 +93,25 @@ func TestBuildPrompt_CustomCommitTypes(t *testing.T) {
 
 func TestBuildPrompt_EmptyStagedDiff(t *testing.T) {
 	cfg := setupTestConfig()
-	provider := GeminiProvider{cfg: cfg}
 	stagedDiff := ""
 
-	prompt, err := provider.buildPrompt(stagedDiff, "")
+	prompt, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
 	if err != nil {
-		t.Fatalf("buildPrompt failed: %v", err)
+		t.Fatalf("BuildPrompt failed: %v", err)
 	}
 
-	if !strings.Contains(prompt, "```diff\n\n```") {
-		t.Errorf("prompt does not correctly handle empty staged diff")
+	// The staged diff section is empty up to the commit types.
+	_, diffSection, _ := strings.Cut(prompt, "**STAGED DIFF:**")
+	if diffSection, _, _ = strings.Cut(diffSection, "**COMMIT TYPES:**"); strings.TrimSpace(diffSection) != "" {
+		t.Errorf("prompt does not correctly handle empty staged diff, got %q", diffSection)
 	}
 }
 
 func TestBuildPrompt_InvalidTemplate(t *testing.T) {
 	cfg := setupTestConfig()
 	cfg.Prompt.Template = "{{.StagedDiff" // Malformed template: Missing closing '}}'
-	provider := GeminiProvider{cfg: cfg}
 
-	_, err := provider.buildPrompt(stagedDiff, "")
+	_, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
 	if err == nil {
 		t.Errorf("expected an error for invalid template, got nil")
 	}
@@ -131,33 +140,165 @@ func TestGenerate_WithEnvVar(t *testing.T) {
 
 	stagedDiff := "diff --git a/main.go b/main.go\nindex 0000000..abcdef0 100644\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,7 @@\n package main\n \n import (\n+\t\"fmt\"\n \t\"log\"\n )\n \n+func greet() {\n+\tfmt.Println(\"Hello, world!\")\n}\n+\n func main() {\n \tlog.Println(\"Starting application\")\n+\tgreet()\n }"
 
-	message, err := provider.Generate(context.Background(), stagedDiff, "")
+	resp, err := provider.Generate(context.Background(), GenerateRequest{PromptData: NewPromptData(cfg, stagedDiff, "")})
 	if err != nil {
 		t.Fatalf("Generate test failed: %v", err)
 	}
 
-	if message == "" {
+	if resp.Message == "" {
 		t.Errorf("expected a non-empty commit message, got empty")
 	}
 
 	// Basic check for conventional commit format
-	if !strings.Contains(message, ":") || !strings.Contains(message, "\n\n") {
-		t.Logf("Generated message: %s", message)
+	if !strings.Contains(resp.Message, ":") || !strings.Contains(resp.Message, "\n\n") {
+		t.Logf("Generated message: %s", resp.Message)
 		t.Errorf("generated message does not seem to follow conventional commit format")
 	}
 }
 
+func TestBuildSystemPrompt(t *testing.T) {
+	cfg := setupTestConfig()
+	data := NewPromptData(cfg, stagedDiff, "")
+
+	system, err := BuildSystemPrompt(cfg, data)
+	if err != nil {
+		t.Fatalf("BuildSystemPrompt failed: %v", err)
+	}
+	if !strings.Contains(system, "**RULES:**") || strings.Contains(system, stagedDiff) {
+		t.Errorf("expected the rules without the staged diff, got:\n%s", system)
+	}
+
+	data.JSONOutput = true
+	if system, _ := BuildSystemPrompt(cfg, data); !strings.Contains(system, "only the JSON object") {
+		t.Errorf("expected the JSON format instructions, got:\n%s", system)
+	}
+
+	data.Template = cfg.Prompt.SummaryTemplate
+	if system, _ := BuildSystemPrompt(cfg, data); system != "" {
+		t.Errorf("expected no system prompt for a request with its own template, got %q", system)
+	}
+
+	cfg.Prompt.SystemTemplate = ""
+	if system, _ := BuildSystemPrompt(cfg, NewPromptData(cfg, stagedDiff, "")); system != "" {
+		t.Errorf("expected an empty system_template to disable the system prompt, got %q", system)
+	}
+}
+
 func TestBuildPrompt_ExistingCommitMessage(t *testing.T) {
 	cfg := setupTestConfig()
-	provider := GeminiProvider{cfg: cfg}
 	existingMsg := "feat: existing feature\n\nThis is an existing message."
 
-	prompt, err := provider.buildPrompt(stagedDiff, existingMsg)
+	prompt, err := BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, existingMsg))
 	if err != nil {
-		t.Fatalf("buildPrompt failed: %v", err)
+		t.Fatalf("BuildPrompt failed: %v", err)
 	}
 
 	if !strings.Contains(prompt, existingMsg) {
 		t.Errorf("prompt missing existing commit message")
 	}
 }
+
+func TestNewGeminiClientConfig_Backends(t *testing.T) {
+	t.Run("defaults to the Gemini API", func(t *testing.T) {
+		clientCfg, err := newGeminiClientConfig(config.ProviderConfig{APIKey: "test-api-key"})
+		if err != nil {
+			t.Fatalf("newGeminiClientConfig failed: %v", err)
+		}
+		if clientCfg.Backend != genai.BackendGeminiAPI || clientCfg.APIKey != "test-api-key" {
+			t.Errorf("expected the Gemini API backend with the API key, got %+v", clientCfg)
+		}
+	})
+
+	t.Run("vertex_ai uses project and location", func(t *testing.T) {
+		clientCfg, err := newGeminiClientConfig(config.ProviderConfig{
+			Backend:  "vertex_ai",
+			Project:  "acme-prod",
+			Location: "europe-west4",
+		})
+		if err != nil {
+			t.Fatalf("newGeminiClientConfig failed: %v", err)
+		}
+		if clientCfg.Backend != genai.BackendVertexAI {
+			t.Errorf("expected the Vertex AI backend, got %v", clientCfg.Backend)
+		}
+		if clientCfg.Project != "acme-prod" || clientCfg.Location != "europe-west4" {
+			t.Errorf("expected project and location to be set, got %+v", clientCfg)
+		}
+		if clientCfg.APIKey != "" {
+			t.Errorf("expected no API key for the Vertex AI backend")
+		}
+	})
+
+	t.Run("vertex_ai without a project returns an error", func(t *testing.T) {
+		_, err := newGeminiClientConfig(config.ProviderConfig{Backend: "vertex_ai", Location: "us-central1"})
+		if err == nil {
+			t.Fatal("expected an error for a missing project, got nil")
+		}
+	})
+
+	t.Run("missing credentials file returns an error", func(t *testing.T) {
+		_, err := newGeminiClientConfig(config.ProviderConfig{
+			Backend:         "vertex_ai",
+			Project:         "acme-prod",
+			Location:        "us-central1",
+			CredentialsFile: filepath.Join(t.TempDir(), "missing.json"),
+		})
+		if err == nil {
+			t.Fatal("expected an error for a missing credentials file, got nil")
+		}
+	})
+
+	t.Run("unknown backend returns an error", func(t *testing.T) {
+		if _, err := newGeminiClientConfig(config.ProviderConfig{Backend: "bard"}); err == nil {
+			t.Fatal("expected an error for an unknown backend, got nil")
+		}
+	})
+}
+
+func TestBuildPrompt_RepoContext(t *testing.T) {
+	cfg := setupTestConfig()
+	data := NewPromptData(cfg, stagedDiff, "")
+	data.RepoContext = git.RepoContext{
+		RepoName:      "commitgen",
+		Branch:        "main",
+		RecentCommits: []string{"feat(ai): add mock provider"},
+		StagedFiles:   []git.StagedFile{{Status: "R", Path: "new.go", OldPath: "old.go"}},
+	}
+
+	prompt, err := BuildPrompt(cfg, data)
+	if err != nil {
+		t.Fatalf("BuildPrompt failed: %v", err)
+	}
+
+	for _, expected := range []string{"commitgen (branch: main)", "- feat(ai): add mock provider", "- R old.go -> new.go"} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("prompt missing %q", expected)
+		}
+	}
+}
+
+func TestBuildPrompt_ScopesAndLanguage(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.Prompt.Scopes = []string{"ai", "config"}
+	cfg.Prompt.Language = "German"
+
+	system, prompt, err := buildPrompts(cfg, NewPromptData(cfg, stagedDiff, ""))
+	if err != nil {
+		t.Fatalf("buildPrompts failed: %v", err)
+	}
+
+	for _, expected := range []string{"written in German", "- ai\n", "- config\n"} {
+		if !strings.Contains(joinPrompts(system, prompt), expected) {
+			t.Errorf("prompts missing %q", expected)
+		}
+	}
+
+	cfg.Prompt.Scopes = []string{}
+	prompt, err = BuildPrompt(cfg, NewPromptData(cfg, stagedDiff, ""))
+	if err != nil {
+		t.Fatalf("BuildPrompt failed: %v", err)
+	}
+	if strings.Contains(prompt, "SCOPES") {
+		t.Errorf("prompt contains the scopes section without configured scopes")
+	}
+}
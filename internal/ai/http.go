@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+/*
+newHTTPClient builds the HTTP client of a provider from its network settings: an explicit
+proxy, extra trusted CAs, a client certificate for mutual TLS and skipping TLS verification.
+Without any of them it behaves like the default client, including the proxy environment variables.
+*/
+func newHTTPClient(providerType config.ProviderType, providerCfg config.ProviderConfig) (*http.Client, error) {
+	transport, err := newHTTPTransport(providerType, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// newHTTPTransport builds the transport used by newHTTPClient.
+func newHTTPTransport(providerType config.ProviderType, providerCfg config.ProviderConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Credentials in the proxy URL are sent as basic auth to authenticating proxies.
+	if providerCfg.Proxy != "" {
+		proxyURL, err := url.Parse(providerCfg.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy for provider %s: %q", providerType, providerCfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsCfg := &tls.Config{InsecureSkipVerify: providerCfg.InsecureSkipVerify}
+	if providerCfg.CAFile != "" {
+		pem, err := os.ReadFile(providerCfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca_file for provider %s: %w", providerType, err)
+		}
+
+		// The CAs are trusted in addition to the system ones, so other hosts keep working.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in ca_file %s for provider %s", providerCfg.CAFile, providerType)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if providerCfg.ClientCert != "" || providerCfg.ClientKey != "" {
+		if providerCfg.ClientCert == "" || providerCfg.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together for provider %s", providerType)
+		}
+		cert, err := tls.LoadX509KeyPair(providerCfg.ClientCert, providerCfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the client certificate for provider %s: %w", providerType, err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsCfg
+	return transport, nil
+}
+
+// hasNetworkSettings reports whether any of the proxy or TLS settings of a provider are set.
+func hasNetworkSettings(providerCfg config.ProviderConfig) bool {
+	return providerCfg.Proxy != "" || providerCfg.CAFile != "" ||
+		providerCfg.ClientCert != "" || providerCfg.ClientKey != "" || providerCfg.InsecureSkipVerify
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testChatCompletion = `{"choices":[{"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"}]}`
+
+// generateWithNetworkSettings calls an openai_compatible provider at baseURL with the given network settings.
+func generateWithNetworkSettings(t *testing.T, baseURL string, providerCfg config.ProviderConfig) error {
+	t.Helper()
+	cfg := setupTestConfig()
+	providerCfg.Model = "local"
+	providerCfg.BaseURL = baseURL
+	cfg.AI.Providers[config.OpenAICompatible] = providerCfg
+	cfg.SetupLocalProviderOverrides()
+
+	provider, err := NewOpenAICompatibleProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider failed: %v", err)
+	}
+	_, err = provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	return err
+}
+
+// writePEM writes a single PEM block to a new file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// writeTestClientCert creates a self-signed client certificate and returns it with its PEM files.
+func writeTestClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "commitgen"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	dir := t.TempDir()
+	return cert, writePEM(t, dir, "client.pem", "CERTIFICATE", der), writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestHTTPClient_Proxy(t *testing.T) {
+	var proxyAuth, requestURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		requestURL = r.URL.String()
+		w.Write([]byte(testChatCompletion))
+	}))
+	defer proxy.Close()
+
+	proxyURL := "http://user:secret@" + proxy.Listener.Addr().String()
+	if err := generateWithNetworkSettings(t, "http://llm.internal/v1", config.ProviderConfig{Proxy: proxyURL}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if requestURL != "http://llm.internal/v1/chat/completions" {
+		t.Errorf("expected the request to go through the proxy, got %q", requestURL)
+	}
+	if proxyAuth == "" {
+		t.Errorf("expected the proxy credentials to be sent")
+	}
+}
+
+func TestHTTPClient_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testChatCompletion))
+	}))
+	defer server.Close()
+
+	if err := generateWithNetworkSettings(t, server.URL, config.ProviderConfig{}); err == nil {
+		t.Fatal("expected an error for an untrusted certificate, got nil")
+	}
+
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	if err := generateWithNetworkSettings(t, server.URL, config.ProviderConfig{CAFile: caFile}); err != nil {
+		t.Errorf("expected the ca_file to be trusted, got %v", err)
+	}
+	if err := generateWithNetworkSettings(t, server.URL, config.ProviderConfig{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("expected insecure_skip_verify to skip verification, got %v", err)
+	}
+}
+
+func TestHTTPClient_ClientCert(t *testing.T) {
+	clientCert, certFile, keyFile := writeTestClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testChatCompletion))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	if err := generateWithNetworkSettings(t, server.URL, config.ProviderConfig{InsecureSkipVerify: true}); err == nil {
+		t.Fatal("expected an error without a client certificate, got nil")
+	}
+
+	providerCfg := config.ProviderConfig{InsecureSkipVerify: true, ClientCert: certFile, ClientKey: keyFile}
+	if err := generateWithNetworkSettings(t, server.URL, providerCfg); err != nil {
+		t.Errorf("expected the client certificate to be accepted, got %v", err)
+	}
+}
+
+func TestNewHTTPClient_InvalidSettings(t *testing.T) {
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(emptyFile, nil, 0600)
+
+	testCases := []struct {
+		name        string
+		providerCfg config.ProviderConfig
+	}{
+		{"proxy without a host", config.ProviderConfig{Proxy: "proxy.internal"}},
+		{"missing ca_file", config.ProviderConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"ca_file without certificates", config.ProviderConfig{CAFile: emptyFile}},
+		{"client_cert without client_key", config.ProviderConfig{ClientCert: emptyFile}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newHTTPClient(config.OpenAI, tc.providerCfg); err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewGeminiClientConfig_NetworkSettings(t *testing.T) {
+	clientCfg, err := newGeminiClientConfig(config.ProviderConfig{APIKey: "test-api-key", Proxy: "http://proxy.internal:3128"})
+	if err != nil {
+		t.Fatalf("newGeminiClientConfig failed: %v", err)
+	}
+
+	transport, ok := clientCfg.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected the Gemini client to use the configured transport, got %T", clientCfg.HTTPClient.Transport)
+	}
+	proxyURL, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://generativelanguage.googleapis.com", nil))
+	if err != nil || proxyURL.String() != "http://proxy.internal:3128" {
+		t.Errorf("expected the configured proxy, got %v, %v", proxyURL, err)
+	}
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"errors"
+	"math/bits"
+	"strings"
+)
+
+/*
+MaxTokensProvider implements the LLMProvider interface by recovering from responses cut off by
+max_tokens, which thinking models run into when their thinking uses up most of the limit.
+Such requests are retried with max_tokens doubled up to max_tokens_limit, and if the message
+is still cut off, the partial message is returned with FinishReasonMaxTokens instead of an error.
+*/
+type MaxTokensProvider struct {
+	cfg          *config.Config
+	provider     LLMProvider
+	providerType config.ProviderType
+}
+
+// NewMaxTokensProvider wraps provider, whose settings are those of providerType, to recover from truncated responses.
+func NewMaxTokensProvider(cfg *config.Config, provider LLMProvider, providerType config.ProviderType) *MaxTokensProvider {
+	return &MaxTokensProvider{
+		cfg:          cfg,
+		provider:     provider,
+		providerType: providerType,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p MaxTokensProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate forwards the request and retries it with a larger max_tokens while the response is cut
+off. Only an empty partial message, such as one whose tokens all went to thinking, is an error.
+*/
+func (p MaxTokensProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	limit := p.cfg.AI.MaxTokensLimit
+	maxTokens := resolveOptions(p.cfg.AI.Providers[p.providerType], req.Options).MaxTokens
+	// Doubling a positive max_tokens reaches the limit in at most log2(limit) retries.
+	retries := bits.Len32(uint32(max(limit, 0)))
+	for {
+		resp, err := p.provider.Generate(ctx, req)
+
+		var stopped *StoppedError
+		if !errors.As(err, &stopped) || stopped.Reason != FinishReasonMaxTokens {
+			return resp, err
+		}
+
+		if maxTokens != nil && *maxTokens > 0 && *maxTokens < limit && retries > 0 {
+			retries--
+			doubled := int32(min(int64(*maxTokens)*2, int64(limit)))
+			maxTokens = &doubled
+			req.Options.MaxTokens = maxTokens
+			continue
+		}
+
+		if strings.TrimSpace(stopped.Response.Message) == "" {
+			return GenerateResponse{}, err
+		}
+		return stopped.Response, nil
+	}
+}
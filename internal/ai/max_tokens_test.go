@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+/*
+setupTruncatingProvider returns an OpenAIProvider, wrapped in a MaxTokensProvider, whose server
+cuts off every response requested with less than completeAt tokens. The requested limits are
+appended to requested.
+*/
+func setupTruncatingProvider(t *testing.T, completeAt int32, requested *[]int32) *MaxTokensProvider {
+	t.Helper()
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MaxTokens int32 `json:"max_completion_tokens"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		*requested = append(*requested, body.MaxTokens)
+
+		if body.MaxTokens < completeAt {
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add"},"finish_reason":"length"}],"usage":{"total_tokens":10}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"}]}`))
+	})
+
+	provider.cfg.AI.MaxTokensLimit = 16384
+	return NewMaxTokensProvider(provider.cfg, provider, config.OpenAI)
+}
+
+func TestMaxTokensGenerate(t *testing.T) {
+	testCases := []struct {
+		name              string
+		completeAt        int32
+		expectedMessage   string
+		expectedReason    FinishReason
+		expectedRequested []int32
+	}{
+		{
+			name:              "complete response is untouched",
+			completeAt:        0,
+			expectedMessage:   "feat: Add greeting",
+			expectedReason:    FinishReasonStop,
+			expectedRequested: []int32{4096},
+		},
+		{
+			name:              "truncated response is retried with a doubled limit",
+			completeAt:        8000,
+			expectedMessage:   "feat: Add greeting",
+			expectedReason:    FinishReasonStop,
+			expectedRequested: []int32{4096, 8192},
+		},
+		{
+			name:              "partial message is returned once the limit is reached",
+			completeAt:        100000,
+			expectedMessage:   "feat: Add",
+			expectedReason:    FinishReasonMaxTokens,
+			expectedRequested: []int32{4096, 8192, 16384},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested []int32
+			provider := setupTruncatingProvider(t, tc.completeAt, &requested)
+
+			resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Message != tc.expectedMessage || resp.FinishReason != tc.expectedReason {
+				t.Errorf("expected %q (%s), got %q (%s)", tc.expectedMessage, tc.expectedReason, resp.Message, resp.FinishReason)
+			}
+			if !slices.Equal(requested, tc.expectedRequested) {
+				t.Errorf("expected max_tokens %v to be requested, got %v", tc.expectedRequested, requested)
+			}
+		})
+	}
+}
+
+func TestMaxTokensGenerate_EmptyPartial(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"length"}]}`))
+	})
+	provider.cfg.AI.MaxTokensLimit = 0
+
+	_, err := NewMaxTokensProvider(provider.cfg, provider, config.OpenAI).Generate(context.Background(), newTestRequest(stagedDiff))
+	var stopped *StoppedError
+	if !errors.As(err, &stopped) || stopped.Reason != FinishReasonMaxTokens {
+		t.Errorf("expected a max_tokens error when nothing was generated, got %v", err)
+	}
+}
+
+func TestMaxTokensGenerate_NonPositiveLimit(t *testing.T) {
+	requests := 0
+	providerCfg := openAITestConfig
+	providerCfg.MaxTokens = new(int32)
+	provider := setupTestProvider(t, config.OpenAI, providerCfg, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add"},"finish_reason":"length"}]}`))
+	})
+	provider.cfg.AI.MaxTokensLimit = 16384
+
+	resp, err := NewMaxTokensProvider(provider.cfg, provider, config.OpenAI).Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Add" || resp.FinishReason != FinishReasonMaxTokens {
+		t.Errorf("expected the partial message, got %q (%s)", resp.Message, resp.FinishReason)
+	}
+	if requests != 1 {
+		t.Errorf("expected a max_tokens of 0 not to be doubled, got %d requests", requests)
+	}
+}
+
+func TestGetProvider_NonPositiveMaxTokens(t *testing.T) {
+	for _, maxTokens := range []int32{0, -1} {
+		cfg := setupTestConfig()
+		cfg.AI.MaxTokens = maxTokens
+		cfg.SetupLocalProviderOverrides()
+
+		if _, err := GetProvider(cfg); err == nil || !strings.Contains(err.Error(), "must be positive") {
+			t.Errorf("expected max_tokens %d to be rejected, got %v", maxTokens, err)
+		}
+	}
+}
+
+func TestStoppedError(t *testing.T) {
+	testCases := []struct {
+		reason   FinishReason
+		expected string
+	}{
+		{FinishReasonMaxTokens, "raise max_tokens"},
+		{FinishReasonSafety, "safety filters"},
+		{FinishReasonRecitation, "unstage vendored and third-party files"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.reason), func(t *testing.T) {
+			err := newStoppedError(tc.reason, "REASON")
+			if !strings.Contains(err.Error(), tc.expected) || !strings.Contains(err.Error(), "REASON") {
+				t.Errorf("expected the error to contain %q and the provider reason, got %q", tc.expected, err.Error())
+			}
+			if !errors.Is(err, ErrGenerationStopped) {
+				t.Error("expected the error to match ErrGenerationStopped")
+			}
+		})
+	}
+}
+
+func TestGeminiCandidateText(t *testing.T) {
+	candidate := &genai.Candidate{
+		Content: genai.NewContentFromParts([]*genai.Part{
+			{Text: "The diff adds a greeting.", Thought: true},
+			{Text: "feat: Add greeting"},
+		}, genai.RoleModel),
+	}
+
+	text, thoughts := geminiCandidateText(candidate)
+	if text != "feat: Add greeting" || thoughts != "The diff adds a greeting." {
+		t.Errorf("expected the thoughts to be kept out of the message, got text %q and thoughts %q", text, thoughts)
+	}
+	if geminiFinishReason(genai.FinishReasonRecitation) != FinishReasonRecitation {
+		t.Error("expected RECITATION to get its own finish reason")
+	}
+}
@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"CommitGen/internal/git"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+/*
+defaultMockTemplate is the message template of the mock provider when none is configured.
+It names every changed file, so the output is deterministic for a given staged diff.
+*/
+const defaultMockTemplate = `{{.Type}}: Update {{.Files}} file{{if ne .Files 1}}s{{end}}
+{{if .Paths}}
+{{range .Paths}}- Update {{.}}
+{{end}}{{end}}`
+
+/*
+MockProvider implements the LLMProvider interface without any network access. It returns the
+configured canned responses in turn, or renders a message from the diff stat of the staged
+changes, which makes it suitable for offline use and for testing everything above the providers.
+*/
+type MockProvider struct {
+	cfg      *config.Config
+	template *template.Template
+
+	// mu guards calls, since candidates are generated by parallel calls.
+	mu    sync.Mutex
+	calls int
+}
+
+// mockMessageData is the data available to the mock provider's message template.
+type mockMessageData struct {
+	Type      string
+	Files     int
+	Additions int
+	Deletions int
+	Paths     []string
+}
+
+// NewMockProvider creates and initializes a new MockProvider instance with the given configuration.
+func NewMockProvider(cfg *config.Config) (*MockProvider, error) {
+	text := cfg.AI.Providers[config.Mock].MessageTemplate
+	if text == "" {
+		text = defaultMockTemplate
+	}
+
+	tmpl, err := template.New("mock").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message_template for provider %s: %w", config.Mock, err)
+	}
+
+	provider := &MockProvider{
+		cfg:      cfg,
+		template: tmpl,
+	}
+	return provider, nil
+}
+
+// Capabilities reports the optional features supported by the mock provider.
+func (p *MockProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, JSONMode: true}
+}
+
+/*
+Generate returns the next canned response, or the rendered message template when no responses
+are configured. The message is streamed line by line and its usage is estimated from the prompt.
+*/
+func (p *MockProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	providerCfg := p.cfg.AI.Providers[config.Mock]
+	message, err := p.nextMessage(providerCfg.Responses, req)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	if req.Stream != nil {
+		var partial strings.Builder
+		for line := range strings.SplitAfterSeq(message, "\n") {
+			if err := ctx.Err(); err != nil {
+				return GenerateResponse{}, err
+			}
+			partial.WriteString(line)
+			req.Stream(partial.String())
+		}
+	}
+
+	promptTokens, outputTokens := estimateTokens(joinPrompts(system, prompt)), estimateTokens(message)
+	return GenerateResponse{
+		Message:      message,
+		FinishReason: FinishReasonStop,
+		Usage: Usage{
+			PromptTokens: promptTokens,
+			OutputTokens: outputTokens,
+			TotalTokens:  promptTokens + outputTokens,
+		},
+		Provider: config.Mock,
+		Model:    providerCfg.Model,
+	}, nil
+}
+
+// nextMessage returns the canned response for this call, or renders the message template.
+func (p *MockProvider) nextMessage(responses []string, req GenerateRequest) (string, error) {
+	p.mu.Lock()
+	call := p.calls
+	p.calls++
+	p.mu.Unlock()
+
+	if len(responses) > 0 {
+		return responses[call%len(responses)], nil
+	}
+
+	data := newMockMessageData(req.PromptData)
+	var buf bytes.Buffer
+	if err := p.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render message_template for provider %s: %w", config.Mock, err)
+	}
+
+	message := strings.TrimSpace(buf.String())
+	if !req.Options.JSON {
+		return message, nil
+	}
+	return mockCommitMessageJSON(data.Type, message)
+}
+
+// newMockMessageData computes the diff stat of the staged changes and picks the commit type.
+func newMockMessageData(promptData PromptData) mockMessageData {
+	data := mockMessageData{Type: promptData.DefaultCommitType}
+	if promptData.ForcedCommitType != "" {
+		data.Type = promptData.ForcedCommitType
+	}
+
+	for _, file := range git.ParseDiff(promptData.StagedDiff) {
+		data.Files++
+		data.Additions += file.Additions
+		data.Deletions += file.Deletions
+		data.Paths = append(data.Paths, file.Path)
+	}
+	return data
+}
+
+/*
+mockCommitMessageJSON turns a rendered message into a CommitMessage document: the text after
+the type on the first line is the subject and every following non-empty line is a body bullet.
+*/
+func mockCommitMessageJSON(commitType, message string) (string, error) {
+	header, body, _ := strings.Cut(message, "\n")
+	commitMessage := CommitMessage{
+		Type:    commitType,
+		Subject: strings.TrimSpace(strings.TrimPrefix(header, commitType+":")),
+		Body:    []string{},
+		Footers: []Footer{},
+	}
+	for line := range strings.SplitSeq(body, "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-")); line != "" {
+			commitMessage.Body = append(commitMessage.Body, line)
+		}
+	}
+
+	data, err := json.Marshal(commitMessage)
+	if err != nil {
+		return "", fmt.Errorf("could not encode commit message: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMockGenerate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		forcedType      string
+		messageTemplate string
+		expected        string
+	}{
+		{
+			name:     "default template",
+			expected: "refactor: Update 1 file\n\n- Update file.go",
+		},
+		{
+			name:       "forced commit type",
+			forcedType: "fix",
+			expected:   "fix: Update 1 file\n\n- Update file.go",
+		},
+		{
+			name:            "custom template",
+			messageTemplate: "{{.Type}}: Change {{.Files}} files (+{{.Additions}} -{{.Deletions}})",
+			expected:        "refactor: Change 1 files (+2 -0)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := setupTestConfig()
+			cfg.ForcedCommitType = tc.forcedType
+			cfg.AI.Providers[config.Mock] = config.ProviderConfig{MessageTemplate: tc.messageTemplate}
+
+			provider, err := NewMockProvider(cfg)
+			if err != nil {
+				t.Fatalf("NewMockProvider failed: %v", err)
+			}
+
+			var partials []string
+			req := GenerateRequest{
+				PromptData: NewPromptData(cfg, stagedDiff, ""),
+				Stream:     func(partial string) { partials = append(partials, partial) },
+			}
+			resp, err := provider.Generate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+
+			if resp.Message != tc.expected {
+				t.Errorf("expected message %q, got %q", tc.expected, resp.Message)
+			}
+			if len(partials) == 0 || partials[len(partials)-1] != tc.expected {
+				t.Errorf("expected the message to be streamed, got %q", partials)
+			}
+			if resp.Provider != config.Mock || resp.Usage.PromptTokens == 0 {
+				t.Errorf("expected the mock provider with estimated usage, got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestMockGenerate_Responses(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.Mock] = config.ProviderConfig{Responses: []string{"feat: First", "fix: Second"}}
+	provider, _ := NewMockProvider(cfg)
+
+	var messages []string
+	for range 3 {
+		resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		messages = append(messages, resp.Message)
+	}
+
+	expected := []string{"feat: First", "fix: Second", "feat: First"}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("expected the responses in turn %q, got %q", expected, messages)
+	}
+}
+
+func TestMockGenerate_InvalidTemplate(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.Mock] = config.ProviderConfig{MessageTemplate: "{{.Type"}
+
+	if _, err := NewMockProvider(cfg); err == nil {
+		t.Fatal("expected an error for an invalid message_template, got nil")
+	}
+}
+
+func TestGetProvider_Mock(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.Mock
+	cfg.AI.OutputFormat = config.OutputFormatJSON
+	cfg.SetupLocalProviderOverrides()
+
+	provider, err := GetProvider(cfg)
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+
+	req := newTestRequest(stagedDiff)
+	req.Options.Candidates = 2
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(resp.Candidates) != 2 || len(resp.CommitMessages) != 2 {
+		t.Fatalf("expected 2 parsed candidates, got %+v", resp)
+	}
+	commitMessage := resp.CommitMessages[0]
+	if commitMessage.Type != "refactor" || commitMessage.Subject != "Update 1 file" || !slices.Equal(commitMessage.Body, []string{"Update file.go"}) {
+		t.Errorf("unexpected structured commit message %+v", commitMessage)
+	}
+	if resp.Model != "mock" {
+		t.Errorf("expected the default mock model, got %q", resp.Model)
+	}
+}
@@ -0,0 +1,243 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const ollamaDefaultHost = "http://localhost:11434"
+
+// OllamaProvider implements the LLMProvider interface for interacting with a local or remote Ollama server.
+type OllamaProvider struct {
+	cfg    *config.Config
+	client *http.Client
+	host   string
+}
+
+// ollamaChatRequest is the request body sent to Ollama's /api/chat endpoint.
+type ollamaChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   any            `json:"format,omitempty"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is the subset of Ollama's /api/chat response used by commitgen.
+type ollamaChatResponse struct {
+	Message    chatMessage `json:"message"`
+	Done       bool        `json:"done"`
+	DoneReason string      `json:"done_reason"`
+
+	PromptEvalCount int32 `json:"prompt_eval_count"`
+	EvalCount       int32 `json:"eval_count"`
+
+	// Error is only set on a line of a streamed response that failed midway.
+	Error string `json:"error"`
+}
+
+/*
+NewOllamaProvider creates and initializes a new OllamaProvider instance with the given configuration.
+The host is taken from base_url, then the OLLAMA_HOST environment variable, and finally
+defaults to http://localhost:11434.
+*/
+func NewOllamaProvider(cfg *config.Config) (*OllamaProvider, error) {
+	providerCfg := cfg.AI.Providers[config.Ollama]
+	if providerCfg.Model == "" {
+		return nil, fmt.Errorf("missing model for provider %s", config.Ollama)
+	}
+
+	host := providerCfg.BaseURL
+	if host == "" {
+		host = os.Getenv("OLLAMA_HOST")
+	}
+	if host == "" {
+		host = ollamaDefaultHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	client, err := newHTTPClient(config.Ollama, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &OllamaProvider{
+		cfg:    cfg,
+		client: client,
+		host:   strings.TrimSuffix(host, "/"),
+	}
+	return provider, nil
+}
+
+// Capabilities reports the optional features supported by the Ollama provider.
+func (p OllamaProvider) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, JSONMode: true}
+}
+
+/*
+buildOptions merges the generic temperature and max_tokens settings with the free-form
+options from the provider config. Explicit options such as num_predict take precedence.
+*/
+func (p OllamaProvider) buildOptions(opts GenerateOptions) map[string]any {
+	providerCfg := p.cfg.AI.Providers[config.Ollama]
+
+	options := make(map[string]any, len(providerCfg.Options)+2)
+	if opts.Temperature != nil {
+		options["temperature"] = *opts.Temperature
+	}
+	if opts.MaxTokens != nil {
+		options["num_predict"] = *opts.MaxTokens
+	}
+	for key, value := range providerCfg.Options {
+		options[key] = value
+	}
+	return options
+}
+
+/*
+Generate sends the constructed prompt to Ollama's /api/chat endpoint and returns the
+generated commit message. A missing model is reported with a hint to pull it first.
+*/
+func (p OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	providerCfg := p.cfg.AI.Providers[config.Ollama]
+	opts := resolveOptions(providerCfg, req.Options)
+	request := ollamaChatRequest{
+		Model:    providerCfg.Model,
+		Messages: newChatMessages(system, prompt),
+		Stream:   req.Stream != nil,
+		Options:  p.buildOptions(opts),
+	}
+	// Ollama constrains the output to a JSON schema passed as the format.
+	if opts.JSON {
+		request.Format = commitMessageSchema
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.host+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, value := range providerCfg.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return GenerateResponse{}, err
+		}
+		return GenerateResponse{}, fmt.Errorf("could not reach Ollama at %s (is 'ollama serve' running?): %w", p.host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		apiErr := newAPIError(config.Ollama, resp, respBody)
+		if isOllamaModelMissing(apiErr) {
+			return GenerateResponse{}, fmt.Errorf(
+				"model %q is not available on %s, run 'ollama pull %s' first: %w",
+				providerCfg.Model, p.host, providerCfg.Model, apiErr,
+			)
+		}
+		return GenerateResponse{}, apiErr
+	}
+
+	result, err := decodeOllamaChat(resp.Body, req.Stream)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	response := GenerateResponse{
+		Message:      result.Message.Content,
+		FinishReason: FinishReasonStop,
+		Usage: Usage{
+			PromptTokens: result.PromptEvalCount,
+			OutputTokens: result.EvalCount,
+			TotalTokens:  result.PromptEvalCount + result.EvalCount,
+		},
+		Provider: config.Ollama,
+		Model:    providerCfg.Model,
+	}
+
+	// Older Ollama versions omit done_reason, so only a reported reason other than 'stop' is an error.
+	if !result.Done || (result.DoneReason != "" && result.DoneReason != "stop") {
+		// Ollama reports the same reasons as the Chat Completions API, such as 'length'.
+		stopped := newStoppedError(openAIFinishReason(result.DoneReason), result.DoneReason)
+		stopped.Response = response
+		stopped.Response.FinishReason = stopped.Reason
+		return GenerateResponse{}, stopped
+	}
+
+	if response.Message == "" {
+		return GenerateResponse{}, fmt.Errorf("AI returned a message with empty content")
+	}
+	return response, nil
+}
+
+/*
+decodeOllamaChat reads an /api/chat response body. When stream is set, the body holds one JSON
+object per line that are merged into a single response while stream receives the text so far.
+*/
+func decodeOllamaChat(body io.Reader, stream func(partial string)) (ollamaChatResponse, error) {
+	var result ollamaChatResponse
+	if stream == nil {
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return result, fmt.Errorf("could not decode response: %w", err)
+		}
+		return result, nil
+	}
+
+	var text strings.Builder
+	err := readJSONLines(body, func(line []byte) error {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("could not decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama stream failed: %s", chunk.Error)
+		}
+
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			stream(text.String())
+		}
+		if chunk.Done {
+			result = chunk
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Message = chatMessage{Role: "assistant", Content: text.String()}
+	return result, nil
+}
+
+// isOllamaModelMissing reports whether an Ollama error means the model has not been pulled yet.
+func isOllamaModelMissing(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound && strings.Contains(apiErr.Message, "not found")
+}
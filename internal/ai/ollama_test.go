@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// ollamaTestConfig is the [ai.providers.ollama] table of the Ollama provider tests.
+var ollamaTestConfig = config.ProviderConfig{
+	Model:   "llama-test",
+	Options: map[string]any{"num_ctx": int64(16384)},
+}
+
+func TestOllamaGenerate_Success(t *testing.T) {
+	provider := setupTestProvider(t, config.Ollama, ollamaTestConfig, GetProvider, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.Stream {
+			t.Errorf("expected a non-streaming request")
+		}
+		if req.Options["num_ctx"] != float64(16384) {
+			t.Errorf("expected num_ctx to be passed through, got %v", req.Options["num_ctx"])
+		}
+		if req.Options["num_predict"] != float64(4096) {
+			t.Errorf("expected num_predict to mirror max_tokens, got %v", req.Options["num_predict"])
+		}
+
+		w.Write([]byte(`{"model":"llama-test","message":{"role":"assistant","content":"docs: Update README"},"done":true,"done_reason":"stop"}`))
+	})
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "docs: Update README" {
+		t.Errorf("expected 'docs: Update README', got %q", resp.Message)
+	}
+}
+
+func TestOllamaGenerate_ModelNotPulled(t *testing.T) {
+	provider := setupTestProvider(t, config.Ollama, ollamaTestConfig, GetProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"llama-test\" not found, try pulling it first"}`))
+	})
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil {
+		t.Fatal("expected an error for a missing model, got nil")
+	}
+	if !strings.Contains(err.Error(), "ollama pull llama-test") {
+		t.Errorf("expected a hint to pull the model, got %v", err)
+	}
+}
+
+func TestNewOllamaProvider_HostFromEnv(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "build-box:11434")
+
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.Ollama] = config.ProviderConfig{Model: "llama-test"}
+
+	provider, err := NewOllamaProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewOllamaProvider failed: %v", err)
+	}
+	if provider.host != "http://build-box:11434" {
+		t.Errorf("expected host 'http://build-box:11434', got %q", provider.host)
+	}
+}
@@ -0,0 +1,364 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const openAIDefaultBaseURL = "https://api.openai.com/v1"
+
+/*
+OpenAIProvider implements the LLMProvider interface for interacting with the OpenAI Chat Completions API.
+It also serves any OpenAI-compatible endpoint (LM Studio, vLLM, llama.cpp server, LocalAI, ...)
+when created through NewOpenAICompatibleProvider.
+*/
+type OpenAIProvider struct {
+	cfg          *config.Config
+	providerType config.ProviderType
+	client       *http.Client
+	baseURL      string
+}
+
+// chatMessage is a single message of a Chat Completions conversation.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// newChatMessages returns the conversation made of the optional system instruction and the prompt.
+func newChatMessages(system, prompt string) []chatMessage {
+	if system == "" {
+		return []chatMessage{{Role: "user", Content: prompt}}
+	}
+	return []chatMessage{{Role: "system", Content: system}, {Role: "user", Content: prompt}}
+}
+
+// chatCompletionRequest is the request body sent to the Chat Completions endpoint.
+type chatCompletionRequest struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	MaxCompletionTokens *int32          `json:"max_completion_tokens,omitempty"`
+	MaxTokens           *int32          `json:"max_tokens,omitempty"`
+	Temperature         *float32        `json:"temperature,omitempty"`
+	N                   int             `json:"n,omitempty"`
+	ResponseFormat      *responseFormat `json:"response_format,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+	StreamOptions       *streamOption   `json:"stream_options,omitempty"`
+}
+
+/*
+responseFormat switches the Chat Completions API to JSON output. OpenAI enforces a JSON schema,
+while compatible endpoints are only asked for a JSON object since schema support varies.
+*/
+type responseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *jsonSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// jsonSchemaSpec names the JSON schema enforced by a 'json_schema' response format.
+type jsonSchemaSpec struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
+}
+
+// streamOption asks OpenAI to append a final chunk carrying the token usage to a stream.
+type streamOption struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatCompletionUsage is the token usage reported by the Chat Completions API.
+type chatCompletionUsage struct {
+	PromptTokens            int32 `json:"prompt_tokens"`
+	CompletionTokens        int32 `json:"completion_tokens"`
+	TotalTokens             int32 `json:"total_tokens"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int32 `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// chatCompletionResponse is the subset of the Chat Completions response used by commitgen.
+type chatCompletionResponse struct {
+	Choices []chatCompletionChoice `json:"choices"`
+	Usage   chatCompletionUsage    `json:"usage"`
+}
+
+// chatCompletionChoice is a single generated message of a Chat Completions response.
+type chatCompletionChoice struct {
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// chatCompletionChunk is a single server-sent event of a streamed Chat Completions response.
+type chatCompletionChunk struct {
+	Choices []struct {
+		Index        int         `json:"index"`
+		Delta        chatMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatCompletionUsage `json:"usage"`
+}
+
+// NewOpenAIProvider creates and initializes a new OpenAIProvider instance with the given configuration.
+func NewOpenAIProvider(cfg *config.Config) (*OpenAIProvider, error) {
+	providerCfg := cfg.AI.Providers[config.OpenAI]
+	if providerCfg.APIKey == "" {
+		return nil, fmt.Errorf("missing API key for provider %s", config.OpenAI)
+	}
+
+	baseURL := providerCfg.BaseURL
+	if baseURL == "" {
+		baseURL = openAIDefaultBaseURL
+	}
+	client, err := newHTTPClient(config.OpenAI, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &OpenAIProvider{
+		cfg:          cfg,
+		providerType: config.OpenAI,
+		client:       client,
+		baseURL:      baseURL,
+	}
+	return provider, nil
+}
+
+/*
+NewOpenAICompatibleProvider creates an OpenAIProvider that talks to a self-hosted or
+third-party endpoint implementing the Chat Completions API. A base URL is required,
+while the API key is optional since most local servers do not check it.
+*/
+func NewOpenAICompatibleProvider(cfg *config.Config) (*OpenAIProvider, error) {
+	providerCfg := cfg.AI.Providers[config.OpenAICompatible]
+	if providerCfg.BaseURL == "" {
+		return nil, fmt.Errorf("missing base_url for provider %s", config.OpenAICompatible)
+	}
+	client, err := newHTTPClient(config.OpenAICompatible, providerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &OpenAIProvider{
+		cfg:          cfg,
+		providerType: config.OpenAICompatible,
+		client:       client,
+		baseURL:      providerCfg.BaseURL,
+	}
+	return provider, nil
+}
+
+/*
+Capabilities reports the optional features supported by the OpenAI provider. Compatible
+endpoints often ignore the 'n' parameter, so only OpenAI itself generates several candidates.
+*/
+func (p OpenAIProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming:          true,
+		JSONMode:           true,
+		MultipleCandidates: p.providerType == config.OpenAI,
+	}
+}
+
+/*
+Generate sends the constructed prompt to the Chat Completions endpoint and returns the
+generated commit message. Non-successful HTTP responses are turned into an *APIError.
+*/
+func (p OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	system, prompt, err := buildPrompts(p.cfg, req.PromptData)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	providerCfg := p.cfg.AI.Providers[p.providerType]
+	opts := resolveOptions(providerCfg, req.Options)
+	request := chatCompletionRequest{
+		Model:       providerCfg.Model,
+		Messages:    newChatMessages(system, prompt),
+		Temperature: opts.Temperature,
+		Stream:      req.Stream != nil,
+	}
+	// OpenAI deprecated 'max_tokens', but most compatible servers only understand that name.
+	if p.providerType == config.OpenAI {
+		request.MaxCompletionTokens = opts.MaxTokens
+		if opts.Candidates > 1 {
+			request.N = opts.Candidates
+		}
+		if opts.JSON {
+			request.ResponseFormat = &responseFormat{
+				Type:       "json_schema",
+				JSONSchema: &jsonSchemaSpec{Name: "commit_message", Strict: true, Schema: commitMessageSchema},
+			}
+		}
+		if request.Stream {
+			request.StreamOptions = &streamOption{IncludeUsage: true}
+		}
+	} else {
+		request.MaxTokens = opts.MaxTokens
+		if opts.JSON {
+			request.ResponseFormat = &responseFormat{Type: "json_object"}
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not encode request: %w", err)
+	}
+
+	url := strings.TrimSuffix(p.baseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return GenerateResponse{}, fmt.Errorf("could not create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if providerCfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+providerCfg.APIKey)
+	}
+	for key, value := range providerCfg.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return GenerateResponse{}, newAPIError(p.providerType, resp, respBody)
+	}
+
+	result, err := decodeChatCompletion(resp.Body, req.Stream)
+	if err != nil {
+		return GenerateResponse{}, err
+	}
+
+	if len(result.Choices) == 0 {
+		return GenerateResponse{}, fmt.Errorf("received an empty response from the AI provider")
+	}
+
+	response := GenerateResponse{
+		Usage: Usage{
+			PromptTokens:   result.Usage.PromptTokens,
+			OutputTokens:   result.Usage.CompletionTokens,
+			ThinkingTokens: result.Usage.CompletionTokensDetails.ReasoningTokens,
+			TotalTokens:    result.Usage.TotalTokens,
+		},
+		Provider: p.providerType,
+		Model:    providerCfg.Model,
+	}
+
+	// Check the finish reasons. Choices that did not 'stop' were truncated or filtered.
+	var messages []string
+	var stopped *StoppedError
+	for _, choice := range result.Choices {
+		if choice.FinishReason != "stop" {
+			if stopped == nil {
+				stopped = newStoppedError(openAIFinishReason(choice.FinishReason), choice.FinishReason)
+				stopped.Response = response
+				stopped.Response.Message = choice.Message.Content
+				stopped.Response.FinishReason = stopped.Reason
+			}
+			continue
+		}
+		if choice.Message.Content != "" {
+			messages = append(messages, choice.Message.Content)
+		}
+	}
+
+	if len(messages) == 0 {
+		if stopped != nil {
+			return GenerateResponse{}, stopped
+		}
+		return GenerateResponse{}, fmt.Errorf("AI returned a choice with empty content")
+	}
+
+	response.Message = messages[0]
+	response.FinishReason = FinishReasonStop
+	if len(messages) > 1 {
+		response.Candidates = messages
+	}
+	return response, nil
+}
+
+/*
+decodeChatCompletion reads a Chat Completions response body. When stream is set, the body is
+a stream of chunks that are merged per choice while stream receives the text of the first one so far.
+*/
+func decodeChatCompletion(body io.Reader, stream func(partial string)) (chatCompletionResponse, error) {
+	var result chatCompletionResponse
+	if stream == nil {
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return result, fmt.Errorf("could not decode response: %w", err)
+		}
+		return result, nil
+	}
+
+	var texts []*strings.Builder
+	var finishReasons []string
+	err := readServerSentEvents(body, func(event, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("could not decode stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			for len(texts) <= choice.Index {
+				texts = append(texts, &strings.Builder{})
+				finishReasons = append(finishReasons, "")
+			}
+
+			if choice.FinishReason != "" {
+				finishReasons[choice.Index] = choice.FinishReason
+			}
+			if choice.Delta.Content != "" {
+				texts[choice.Index].WriteString(choice.Delta.Content)
+				if choice.Index == 0 {
+					stream(texts[0].String())
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for i := range texts {
+		if finishReasons[i] == "" && texts[i].Len() == 0 {
+			continue
+		}
+		result.Choices = append(result.Choices, chatCompletionChoice{
+			Message:      chatMessage{Role: "assistant", Content: texts[i].String()},
+			FinishReason: finishReasons[i],
+		})
+	}
+	return result, nil
+}
+
+// openAIFinishReason maps a Chat Completions finish reason onto the provider-independent FinishReason.
+func openAIFinishReason(reason string) FinishReason {
+	switch reason {
+	case "stop":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonMaxTokens
+	case "content_filter":
+		return FinishReasonSafety
+	}
+	return FinishReasonOther
+}
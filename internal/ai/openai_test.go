@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// openAITestConfig is the [ai.providers.openai] table of the OpenAI provider tests.
+var openAITestConfig = config.ProviderConfig{
+	APIKey: "test-openai-key",
+	Model:  "gpt-test",
+}
+
+func TestNewOpenAIProvider_MissingAPIKey(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.OpenAI] = config.ProviderConfig{Model: "gpt-test"}
+
+	if _, err := NewOpenAIProvider(cfg); err == nil {
+		t.Fatal("expected an error for a missing API key, got nil")
+	}
+}
+
+func TestOpenAIGenerate_Success(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-openai-key" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.Model != "gpt-test" {
+			t.Errorf("expected model 'gpt-test', got %q", req.Model)
+		}
+		if req.MaxCompletionTokens == nil || *req.MaxCompletionTokens != 4096 {
+			t.Errorf("expected max_completion_tokens to fall back to the global 4096")
+		}
+		if len(req.Messages) != 2 || req.Messages[0].Role != "system" || strings.Contains(req.Messages[0].Content, stagedDiff) {
+			t.Errorf("expected a system message without the staged diff, got %+v", req.Messages)
+		}
+		if len(req.Messages) != 2 || req.Messages[1].Role != "user" || !strings.Contains(req.Messages[1].Content, stagedDiff) {
+			t.Errorf("expected a user message containing the staged diff, got %+v", req.Messages)
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"}],"usage":{"prompt_tokens":120,"completion_tokens":12,"total_tokens":132}}`))
+	})
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Add greeting" {
+		t.Errorf("expected 'feat: Add greeting', got %q", resp.Message)
+	}
+	if resp.FinishReason != FinishReasonStop {
+		t.Errorf("expected finish reason %q, got %q", FinishReasonStop, resp.FinishReason)
+	}
+	if resp.Provider != config.OpenAI || resp.Model != "gpt-test" {
+		t.Errorf("expected provider 'openai' and model 'gpt-test', got %q and %q", resp.Provider, resp.Model)
+	}
+	if resp.Usage.PromptTokens != 120 || resp.Usage.OutputTokens != 12 || resp.Usage.TotalTokens != 132 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIGenerate_RequestOptionsOverride(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.MaxCompletionTokens == nil || *req.MaxCompletionTokens != 256 {
+			t.Errorf("expected the request's max tokens to win over the config")
+		}
+		if req.Temperature == nil || *req.Temperature != 0.9 {
+			t.Errorf("expected the request's temperature to win over the config")
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"}]}`))
+	})
+
+	maxTokens, temperature := int32(256), float32(0.9)
+	req := newTestRequest(stagedDiff)
+	req.Options = GenerateOptions{MaxTokens: &maxTokens, Temperature: &temperature}
+
+	if _, err := provider.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+}
+
+func TestOpenAIGenerate_APIError(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`))
+	})
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Message != "Incorrect API key provided" {
+		t.Errorf("expected the error message from the body, got %q", apiErr.Message)
+	}
+}
+
+func TestOpenAIGenerate_FinishReasonLength(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add"},"finish_reason":"length"}]}`))
+	})
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil || !strings.Contains(err.Error(), "length") {
+		t.Errorf("expected a finish reason error, got %v", err)
+	}
+
+	var stopped *StoppedError
+	if !errors.As(err, &stopped) || stopped.Response.Message != "feat: Add" {
+		t.Errorf("expected the partial message to be kept in the error, got %v", err)
+	}
+}
+
+func TestOpenAICompatibleGenerate_EndToEnd(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no Authorization header without an API key, got %q", auth)
+		}
+		if gateway := r.Header.Get("X-Gateway-Team"); gateway != "platform" {
+			t.Errorf("expected custom header 'X-Gateway-Team: platform', got %q", gateway)
+		}
+
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.MaxTokens == nil || req.MaxCompletionTokens != nil {
+			t.Errorf("expected max_tokens to be sent instead of max_completion_tokens")
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"fix: Handle local model"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.OpenAICompatible
+	cfg.AI.Providers[config.OpenAICompatible] = config.ProviderConfig{
+		Model:   "qwen2.5-coder",
+		BaseURL: server.URL + "/v1/",
+		Headers: map[string]string{"X-Gateway-Team": "platform"},
+	}
+	cfg.SetupLocalProviderOverrides()
+
+	provider, err := GetProvider(cfg)
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "fix: Handle local model" {
+		t.Errorf("expected 'fix: Handle local model', got %q", resp.Message)
+	}
+}
+
+func TestNewOpenAICompatibleProvider_MissingBaseURL(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.OpenAICompatible] = config.ProviderConfig{Model: "local"}
+
+	if _, err := NewOpenAICompatibleProvider(cfg); err == nil {
+		t.Fatal("expected an error for a missing base_url, got nil")
+	}
+}
+
+func TestOpenAIGenerate_MultipleCandidates(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if req.N != 3 {
+			t.Errorf("expected n = 3, got %d", req.N)
+		}
+
+		w.Write([]byte(`{"choices":[` +
+			`{"index":0,"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"},` +
+			`{"index":1,"message":{"role":"assistant","content":"feat: Greet the user"},"finish_reason":"stop"},` +
+			`{"index":2,"message":{"role":"assistant","content":"feat: Say hel"},"finish_reason":"length"}]}`))
+	})
+
+	req := newTestRequest(stagedDiff)
+	req.Options.Candidates = 3
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := []string{"feat: Add greeting", "feat: Greet the user"}
+	if !slices.Equal(resp.Candidates, expected) {
+		t.Errorf("expected truncated choices to be dropped, got %q", resp.Candidates)
+	}
+	if resp.Message != expected[0] {
+		t.Errorf("expected Message %q, got %q", expected[0], resp.Message)
+	}
+}
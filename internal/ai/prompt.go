@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"CommitGen/internal/git"
+	"bytes"
+	"text/template"
+)
+
+/*
+NewPromptData collects the prompt data for the given staged diff and the configured commit types.
+The repository context is left empty, since it is only available inside a repository.
+*/
+func NewPromptData(cfg *config.Config, stagedDiff, existingCommitMessage string) PromptData {
+	data := PromptData{
+		StagedDiff:            stagedDiff,
+		CommitTypes:           cfg.Prompt.CommitTypes,
+		DefaultCommitType:     cfg.DefaultType,
+		ForcedCommitType:      cfg.ForcedCommitType,
+		ExistingCommitMessage: existingCommitMessage,
+		Scopes:                cfg.Prompt.Scopes,
+		Language:              cfg.Prompt.Language,
+	}
+	if files := git.ParseDiff(stagedDiff); len(files) > 0 {
+		data.DiffStat = git.DiffStat(files)
+	}
+	return data
+}
+
+/*
+BuildPrompt constructs the user prompt shared by every provider by executing the
+configured prompt template, or the data's own template if it has one, with the given data.
+*/
+func BuildPrompt(cfg *config.Config, data PromptData) (string, error) {
+	text := cfg.Prompt.Template
+	if data.Template != "" {
+		text = data.Template
+	}
+	return renderPrompt("prompt", text, data)
+}
+
+/*
+BuildSystemPrompt constructs the system instruction sent alongside the user prompt by executing
+the configured system template with the given data. It is empty when no system template is
+configured and for requests with their own template, such as summarization steps.
+*/
+func BuildSystemPrompt(cfg *config.Config, data PromptData) (string, error) {
+	if cfg.Prompt.SystemTemplate == "" || data.Template != "" {
+		return "", nil
+	}
+	return renderPrompt("system", cfg.Prompt.SystemTemplate, data)
+}
+
+/*
+buildPrompts returns both the system instruction and the user prompt for data, for the
+providers that send them as separate messages.
+*/
+func buildPrompts(cfg *config.Config, data PromptData) (system, prompt string, err error) {
+	system, err = BuildSystemPrompt(cfg, data)
+	if err != nil {
+		return "", "", err
+	}
+	prompt, err = BuildPrompt(cfg, data)
+	return system, prompt, err
+}
+
+// joinPrompts returns the system instruction followed by the user prompt as a single text.
+func joinPrompts(system, prompt string) string {
+	if system == "" {
+		return prompt
+	}
+	return system + "\n\n" + prompt
+}
+
+// renderPrompt executes the template text with the given data.
+func renderPrompt(name, text string, data PromptData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+/*
+resolveOptions returns the effective generation settings for a request, preferring the
+request's options over the provider configuration.
+*/
+func resolveOptions(providerCfg config.ProviderConfig, opts GenerateOptions) GenerateOptions {
+	resolved := GenerateOptions{
+		MaxTokens:   providerCfg.MaxTokens,
+		Temperature: providerCfg.Temperature,
+		Candidates:  opts.Candidates,
+		JSON:        opts.JSON,
+	}
+	if opts.MaxTokens != nil {
+		resolved.MaxTokens = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		resolved.Temperature = opts.Temperature
+	}
+	return resolved
+}
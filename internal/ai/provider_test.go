@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+/*
+setupTestProvider returns the provider built by newProvider from the test config, with providerType
+as the default provider and providerCfg as its [ai.providers] table. A non-nil handler is served by an
+httptest server whose URL becomes the provider's base_url.
+*/
+func setupTestProvider[P LLMProvider](t *testing.T, providerType config.ProviderType, providerCfg config.ProviderConfig,
+	newProvider func(*config.Config) (P, error), handler http.HandlerFunc) P {
+	t.Helper()
+	if handler != nil {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		providerCfg.BaseURL = server.URL
+	}
+
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = providerType
+	cfg.AI.Providers[providerType] = providerCfg
+	cfg.SetupLocalProviderOverrides()
+
+	provider, err := newProvider(cfg)
+	if err != nil {
+		t.Fatalf("creating the %s test provider failed: %v", providerType, err)
+	}
+	return provider
+}
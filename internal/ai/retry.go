@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+/*
+RetryProvider implements the LLMProvider interface by retrying another provider on transient
+errors. Delays grow exponentially with jitter, and a Retry-After hint from the provider is
+honored as long as it does not exceed the configured maximum backoff.
+*/
+type RetryProvider struct {
+	provider LLMProvider
+	policy   config.Retry
+}
+
+// NewRetryProvider wraps provider so that transient failures are retried according to policy.
+func NewRetryProvider(provider LLMProvider, policy config.Retry) *RetryProvider {
+	return &RetryProvider{
+		provider: provider,
+		policy:   policy,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p RetryProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate calls the wrapped provider until it succeeds, returns a non-retriable error or the
+maximum number of attempts is reached. Waiting between attempts is aborted when ctx is done.
+*/
+func (p RetryProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := p.provider.Generate(ctx, req)
+		if err == nil || attempt >= p.policy.MaxAttempts || !isRetriable(err) {
+			return resp, err
+		}
+
+		delay, ok := p.backoff(attempt, err)
+		if !ok {
+			return resp, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return GenerateResponse{}, err
+		case <-timer.C:
+		}
+	}
+}
+
+/*
+backoff returns how long to wait before the given retry. A Retry-After hint wins over the
+computed delay; if that hint is longer than the maximum backoff, ok is false and the caller
+should give up rather than block for that long.
+*/
+func (p RetryProvider) backoff(attempt int, err error) (delay time.Duration, ok bool) {
+	maxBackoff := time.Duration(p.policy.MaxBackoff)
+	if retryAfter := retryAfterHint(err); retryAfter > 0 {
+		return retryAfter, maxBackoff <= 0 || retryAfter <= maxBackoff
+	}
+
+	delay = time.Duration(p.policy.InitialBackoff)
+	for i := 1; i < attempt && (maxBackoff <= 0 || delay < maxBackoff); i++ {
+		delay *= 2
+	}
+	if maxBackoff > 0 && delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	// Use "equal jitter" so parallel clients spread out without ever retrying immediately.
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int64N(half+1))
+	}
+	return delay, true
+}
+
+// retryAfterHint returns the Retry-After duration carried by an *APIError, or zero.
+func retryAfterHint(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// isRetriable reports whether err is transient: a rate limit, a server error or a network failure.
+func isRetriable(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorKindRateLimit, ErrorKindServer, ErrorKindNetwork:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails with the given errors in order before succeeding.
+type flakyProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *flakyProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return GenerateResponse{}, p.errs[p.calls-1]
+	}
+	return GenerateResponse{Message: "feat: Retry"}, nil
+}
+
+func (p *flakyProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// testRetryPolicy returns a retry policy with delays short enough for tests.
+func testRetryPolicy(maxAttempts int) config.Retry {
+	return config.Retry{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: config.Duration(time.Millisecond),
+		MaxBackoff:     config.Duration(10 * time.Millisecond),
+	}
+}
+
+func TestRetryGenerate_RetriesTransientErrors(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests},
+		&APIError{StatusCode: http.StatusServiceUnavailable},
+	}}
+	provider := NewRetryProvider(flaky, testRetryPolicy(3))
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Retry" || flaky.calls != 3 {
+		t.Errorf("expected success on the third call, got %q after %d calls", resp.Message, flaky.calls)
+	}
+}
+
+func TestRetryGenerate_GivesUpAfterMaxAttempts(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{
+		&APIError{StatusCode: http.StatusInternalServerError},
+		&APIError{StatusCode: http.StatusInternalServerError},
+		&APIError{StatusCode: http.StatusInternalServerError},
+	}}
+	provider := NewRetryProvider(flaky, testRetryPolicy(2))
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err == nil {
+		t.Fatal("expected an error after exhausting all attempts, got nil")
+	}
+	if flaky.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", flaky.calls)
+	}
+}
+
+func TestRetryGenerate_SkipsNonRetriableErrors(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{&APIError{StatusCode: http.StatusUnauthorized}}}
+	provider := NewRetryProvider(flaky, testRetryPolicy(3))
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err == nil {
+		t.Fatal("expected the auth error to be returned, got nil")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected a single call for an auth error, got %d", flaky.calls)
+	}
+}
+
+func TestRetryGenerate_RetryAfterTooLong(t *testing.T) {
+	flaky := &flakyProvider{errs: []error{&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}}}
+	provider := NewRetryProvider(flaky, testRetryPolicy(3))
+
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err == nil {
+		t.Fatal("expected the rate limit error to be returned, got nil")
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected no retry when Retry-After exceeds max_backoff, got %d calls", flaky.calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	provider := NewRetryProvider(nil, config.Retry{
+		MaxAttempts:    5,
+		InitialBackoff: config.Duration(time.Second),
+		MaxBackoff:     config.Duration(4 * time.Second),
+	})
+
+	testCases := []struct {
+		attempt  int
+		minDelay time.Duration
+		maxDelay time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{6, 2 * time.Second, 4 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		delay, ok := provider.backoff(tc.attempt, &APIError{StatusCode: http.StatusServiceUnavailable})
+		if !ok || delay < tc.minDelay || delay > tc.maxDelay {
+			t.Errorf("attempt %d: expected a delay in [%s, %s], got %s", tc.attempt, tc.minDelay, tc.maxDelay, delay)
+		}
+	}
+
+	delay, ok := provider.backoff(1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second})
+	if !ok || delay != 3*time.Second {
+		t.Errorf("expected the Retry-After hint of 3s to be used, got %s", delay)
+	}
+}
+
+func TestRetryAfterHeader_OpenAI(t *testing.T) {
+	calls := 0
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"feat: Add greeting"},"finish_reason":"stop"}]}`))
+	})
+
+	resp, err := NewRetryProvider(provider, testRetryPolicy(2)).Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Add greeting" || calls != 2 {
+		t.Errorf("expected success on the second call, got %q after %d calls", resp.Message, calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay := parseRetryAfter("7"); delay != 7*time.Second {
+		t.Errorf("expected 7s, got %s", delay)
+	}
+	if delay := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); delay <= 0 || delay > time.Minute {
+		t.Errorf("expected a delay of up to a minute for an HTTP date, got %s", delay)
+	}
+	if delay := parseRetryAfter("soon"); delay != 0 {
+		t.Errorf("expected 0 for an invalid header, got %s", delay)
+	}
+}
+
+func TestGeminiRetryDelay(t *testing.T) {
+	details := []map[string]any{
+		{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+		{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "42s"},
+	}
+	if delay := geminiRetryDelay(details); delay != 42*time.Second {
+		t.Errorf("expected 42s, got %s", delay)
+	}
+}
+
+func TestGetProvider_WrapsRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := setupTestConfig()
+	cfg.AI.DefaultProvider = config.OpenAI
+	cfg.AI.Providers[config.OpenAI] = config.ProviderConfig{APIKey: "test-openai-key", Model: "gpt-test", BaseURL: server.URL}
+
+	provider, err := GetProvider(cfg)
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if _, ok := provider.(*RetryProvider); !ok {
+		t.Errorf("expected the default config to enable retries, got %T", provider)
+	}
+
+	cfg.AI.Retry.MaxAttempts = 1
+	provider, _ = GetProvider(cfg)
+	if _, ok := provider.(*RetryProvider); ok {
+		t.Errorf("expected max_attempts = 1 to disable retries, got %T", provider)
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// maxStreamLineSize bounds a single line of a streamed response body.
+const maxStreamLineSize = 1024 * 1024
+
+/*
+readServerSentEvents reads a text/event-stream body and calls handle with the event name and
+data of every event. Reading stops at the end of the body or when handle returns an error.
+*/
+func readServerSentEvents(body io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		defer func() { event, data = "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		return handle(event, strings.Join(data, "\n"))
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment lines are used as keep-alives.
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+/*
+readJSONLines reads a newline-delimited JSON body, as streamed by Ollama, and calls handle
+with every non-empty line.
+*/
+func readJSONLines(body io.Reader, handle func(line []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := handle(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+/*
+streamWriter is an io.Writer that accumulates everything written to it in buffer and reports
+the text received so far to a GenerateRequest's Stream callback.
+*/
+type streamWriter struct {
+	buffer *bytes.Buffer
+	stream func(partial string)
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	w.stream(w.buffer.String())
+	return len(p), nil
+}
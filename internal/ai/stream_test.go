@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collectStream returns a GenerateRequest that records every partial message passed to Stream.
+func collectStream(partials *[]string) GenerateRequest {
+	req := newTestRequest(stagedDiff)
+	req.Stream = func(partial string) {
+		*partials = append(*partials, partial)
+	}
+	return req
+}
+
+func TestOpenAIGenerate_Stream(t *testing.T) {
+	provider := setupTestProvider(t, config.OpenAI, openAITestConfig, NewOpenAIProvider, func(w http.ResponseWriter, r *http.Request) {
+		var req chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+		if !req.Stream {
+			t.Errorf("expected a streaming request")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": keep-alive\n\n" +
+			`data: {"choices":[{"delta":{"content":"feat: "}}]}` + "\n\n" +
+			`data: {"choices":[{"delta":{"content":"Add greeting"}}]}` + "\n\n" +
+			`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}` + "\n\n" +
+			`data: {"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":12,"total_tokens":132}}` + "\n\n" +
+			"data: [DONE]\n\n"))
+	})
+
+	var partials []string
+	resp, err := provider.Generate(context.Background(), collectStream(&partials))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "feat: Add greeting" {
+		t.Errorf("expected 'feat: Add greeting', got %q", resp.Message)
+	}
+	if resp.Usage.TotalTokens != 132 {
+		t.Errorf("expected usage from the final chunk, got %+v", resp.Usage)
+	}
+	if want := []string{"feat: ", "feat: Add greeting"}; !reflect.DeepEqual(partials, want) {
+		t.Errorf("expected partial messages %q, got %q", want, partials)
+	}
+}
+
+func TestAnthropicGenerate_Stream(t *testing.T) {
+	provider := setupTestProvider(t, config.Anthropic, anthropicTestConfig, NewAnthropicProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\n" +
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":80,"output_tokens":1}}}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"fix: "}}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Handle nil config"}}` + "\n\n" +
+			"event: message_delta\n" +
+			`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":9}}` + "\n\n" +
+			"event: message_stop\n" +
+			`data: {"type":"message_stop"}` + "\n\n"))
+	})
+
+	var partials []string
+	resp, err := provider.Generate(context.Background(), collectStream(&partials))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "fix: Handle nil config" {
+		t.Errorf("expected 'fix: Handle nil config', got %q", resp.Message)
+	}
+	if resp.Usage.PromptTokens != 80 || resp.Usage.OutputTokens != 9 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+	if len(partials) != 2 || partials[1] != resp.Message {
+		t.Errorf("expected two partial messages ending with the full message, got %q", partials)
+	}
+}
+
+func TestAnthropicGenerate_StreamError(t *testing.T) {
+	provider := setupTestProvider(t, config.Anthropic, anthropicTestConfig, NewAnthropicProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: error\n" +
+			`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n"))
+	})
+
+	var partials []string
+	_, err := provider.Generate(context.Background(), collectStream(&partials))
+	if kind := ClassifyError(err); kind != ErrorKindServer {
+		t.Errorf("expected an in-stream overload to be a server error, got %s (%v)", kind, err)
+	}
+}
+
+func TestOllamaGenerate_Stream(t *testing.T) {
+	provider := setupTestProvider(t, config.Ollama, ollamaTestConfig, GetProvider, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"docs: "},"done":false}` + "\n" +
+			`{"message":{"role":"assistant","content":"Update README"},"done":false}` + "\n" +
+			`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":50,"eval_count":4}` + "\n"))
+	})
+
+	var partials []string
+	resp, err := provider.Generate(context.Background(), collectStream(&partials))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Message != "docs: Update README" {
+		t.Errorf("expected 'docs: Update README', got %q", resp.Message)
+	}
+	if len(partials) == 0 || partials[len(partials)-1] != resp.Message {
+		t.Errorf("expected the last partial message to be the full message, got %q", partials)
+	}
+}
+
+func TestReadServerSentEvents(t *testing.T) {
+	body := "event: first\ndata: line one\ndata: line two\n\n: comment\n\ndata: no trailing blank line"
+
+	var got []string
+	err := readServerSentEvents(strings.NewReader(body), func(event, data string) error {
+		got = append(got, event+"|"+data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readServerSentEvents failed: %v", err)
+	}
+
+	want := []string{"first|line one\nline two", "|no trailing blank line"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+}
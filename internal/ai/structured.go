@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+)
+
+/*
+StructuredProvider implements the LLMProvider interface on top of another provider for JSON
+mode. It asks the model for a CommitMessage document, using the provider's native JSON mode
+where available, and every returned candidate is parsed and formatted by commitgen itself.
+*/
+type StructuredProvider struct {
+	provider LLMProvider
+}
+
+// NewStructuredProvider wraps provider so that its JSON output is turned into formatted commit messages.
+func NewStructuredProvider(provider LLMProvider) *StructuredProvider {
+	return &StructuredProvider{
+		provider: provider,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p StructuredProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate switches the request to JSON output and parses the result. Candidates that are not
+valid JSON are dropped, and the parse error is only returned if none of them is. Requests
+with their own template, such as summarization steps, do not ask for a commit message and
+are forwarded unchanged.
+*/
+func (p StructuredProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	if req.PromptData.Template != "" {
+		return p.provider.Generate(ctx, req)
+	}
+
+	req.Options.JSON = true
+	req.PromptData.JSONOutput = true
+	resp, err := p.provider.Generate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	raw := resp.Candidates
+	if len(raw) == 0 {
+		raw = []string{resp.Message}
+	}
+
+	var parseErr error
+	var messages []string
+	resp.CommitMessages = nil
+	for _, candidate := range raw {
+		commitMessage, err := ParseCommitMessage(candidate)
+		if err != nil {
+			parseErr = err
+			continue
+		}
+		resp.CommitMessages = append(resp.CommitMessages, commitMessage)
+		messages = append(messages, commitMessage.String())
+	}
+	if len(messages) == 0 {
+		return GenerateResponse{}, parseErr
+	}
+
+	resp.Message = messages[0]
+	resp.Candidates = nil
+	if len(messages) > 1 {
+		resp.Candidates = messages
+	}
+	return resp, nil
+}
@@ -0,0 +1,321 @@
+package ai
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrNoConventionalCommits is returned when the sampled history has no well-formed commit to learn from.
+var ErrNoConventionalCommits = errors.New("no conventional commits found in the history")
+
+// The body styles of a StyleProfile.
+const (
+	BodyStyleNone       = "none"
+	BodyStyleBullets    = "bullets"
+	BodyStyleParagraphs = "paragraphs"
+)
+
+const (
+	// maxStyleHeaderLength excludes commits whose header is too long to be a good example.
+	maxStyleHeaderLength = 100
+
+	// maxStyleExampleLength excludes commits whose message would take up too much of the prompt.
+	maxStyleExampleLength = 1000
+
+	// maxStyleNames limits how many types, scopes and trailers are listed in the guidelines.
+	maxStyleNames = 10
+)
+
+var (
+	// conventionalHeaderRegexp matches a conventional commit header such as 'feat(ai)!: add JSON mode'.
+	conventionalHeaderRegexp = regexp.MustCompile(`^([a-z]+)(?:\(([^()\s]+)\))?(!)?: (\S.*)$`)
+
+	// trailerRegexp matches a git trailer such as 'Refs: #123' or 'BREAKING CHANGE: ...'.
+	trailerRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|BREAKING CHANGE): \S`)
+)
+
+// StyleCount is how many of the sampled commits use a type, scope or trailer.
+type StyleCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+/*
+StyleProfile holds the commit conventions of a repository, learned from the well-formed commits
+of its history. It is added to the prompt as guidelines and few-shot examples, so the generated
+messages follow the conventions of each repository rather than a single global template.
+*/
+type StyleProfile struct {
+	LearnedAt time.Time `json:"learned_at"`
+
+	// Commits is the number of well-formed commits the profile was learned from.
+	Commits int `json:"commits"`
+
+	// Types, Scopes and Trailers are sorted by how often they are used, most frequent first.
+	Types         []StyleCount `json:"types"`
+	Scopes        []StyleCount `json:"scopes"`
+	ScopedCommits int          `json:"scoped_commits"`
+	Trailers      []StyleCount `json:"trailers"`
+
+	LowercaseSubjects bool   `json:"lowercase_subjects"`
+	SubjectPeriod     bool   `json:"subject_period"`
+	BodyStyle         string `json:"body_style"`
+
+	// Examples are complete messages of past commits, one per frequent type.
+	Examples []string `json:"examples"`
+}
+
+// styleCommit is a well-formed commit message split into the parts the profile is learned from.
+type styleCommit struct {
+	message    string
+	commitType string
+	scope      string
+	subject    string
+	body       string
+	trailers   []string
+}
+
+/*
+LearnStyle learns a StyleProfile from commit messages, newest first, keeping up to examples
+of them as few-shot examples. Only messages with a conventional commit header are considered;
+fixups, reverts and other free-form messages are skipped.
+*/
+func LearnStyle(messages []string, examples int) (StyleProfile, error) {
+	var commits []styleCommit
+	for _, message := range messages {
+		if commit, ok := parseStyleCommit(message); ok {
+			commits = append(commits, commit)
+		}
+	}
+	if len(commits) == 0 {
+		return StyleProfile{}, ErrNoConventionalCommits
+	}
+
+	profile := StyleProfile{LearnedAt: time.Now(), Commits: len(commits)}
+	types, scopes, trailers, bodyStyles := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	var lowercase, period int
+	for _, commit := range commits {
+		types[commit.commitType]++
+		if commit.scope != "" {
+			scopes[commit.scope]++
+			profile.ScopedCommits++
+		}
+		for _, trailer := range commit.trailers {
+			trailers[trailer]++
+		}
+		bodyStyles[bodyStyle(commit.body)]++
+
+		if first := []rune(commit.subject)[0]; unicode.IsLower(first) {
+			lowercase++
+		}
+		if strings.HasSuffix(commit.subject, ".") {
+			period++
+		}
+	}
+
+	profile.Types = sortedStyleCounts(types)
+	profile.Scopes = sortedStyleCounts(scopes)
+	profile.Trailers = sortedStyleCounts(trailers)
+	profile.LowercaseSubjects = lowercase*2 >= len(commits)
+	profile.SubjectPeriod = period*2 > len(commits)
+	profile.BodyStyle = sortedStyleCounts(bodyStyles)[0].Name
+	profile.Examples = styleExamples(commits, profile.Types, examples)
+	return profile, nil
+}
+
+// parseStyleCommit splits a commit message, reporting whether it is well-formed.
+func parseStyleCommit(message string) (styleCommit, bool) {
+	message = strings.TrimSpace(message)
+	header, rest, _ := strings.Cut(message, "\n")
+	match := conventionalHeaderRegexp.FindStringSubmatch(header)
+	if match == nil || len(header) > maxStyleHeaderLength {
+		return styleCommit{}, false
+	}
+
+	commit := styleCommit{
+		message:    message,
+		commitType: match[1],
+		scope:      match[2],
+		subject:    match[4],
+	}
+
+	// The trailers are the last paragraph of the body if all of its lines are trailers.
+	paragraphs := strings.Split(strings.TrimSpace(rest), "\n\n")
+	last := strings.Split(paragraphs[len(paragraphs)-1], "\n")
+	for _, line := range last {
+		match := trailerRegexp.FindStringSubmatch(line)
+		if match == nil {
+			commit.trailers = nil
+			break
+		}
+		commit.trailers = append(commit.trailers, match[1])
+	}
+	if commit.trailers != nil {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	commit.body = strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+	return commit, true
+}
+
+// bodyStyle classifies a commit body as empty, mostly bullet points, or prose.
+func bodyStyle(body string) string {
+	if body == "" {
+		return BodyStyleNone
+	}
+
+	var lines, bullets int
+	for line := range strings.SplitSeq(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines++
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+			bullets++
+		}
+	}
+	if bullets*2 >= lines {
+		return BodyStyleBullets
+	}
+	return BodyStyleParagraphs
+}
+
+// sortedStyleCounts sorts the counted names by count, most frequent first, then by name.
+func sortedStyleCounts(counts map[string]int) []StyleCount {
+	sorted := make([]StyleCount, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, StyleCount{Name: name, Count: count})
+	}
+	slices.SortFunc(sorted, func(a, b StyleCount) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Name, b.Name))
+	})
+	return sorted
+}
+
+/*
+styleExamples picks up to limit commits as examples: the newest commit of each type in order of
+frequency first, then the newest remaining commits. Messages too long for the prompt are skipped.
+*/
+func styleExamples(commits []styleCommit, types []StyleCount, limit int) []string {
+	var examples []string
+	picked := map[int]bool{}
+	pick := func(matches func(styleCommit) bool) bool {
+		for i, commit := range commits {
+			if len(examples) < limit && !picked[i] && len(commit.message) <= maxStyleExampleLength && matches(commit) {
+				examples = append(examples, commit.message)
+				picked[i] = true
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, commitType := range types {
+		pick(func(commit styleCommit) bool { return commit.commitType == commitType.Name })
+	}
+	for pick(func(styleCommit) bool { return true }) {
+	}
+	return examples
+}
+
+/*
+Guidelines describes the conventions of the profile as short sentences for the prompt.
+A profile learned from no commits has no guidelines.
+*/
+func (p StyleProfile) Guidelines() []string {
+	if p.Commits == 0 {
+		return nil
+	}
+
+	var guidelines []string
+	if len(p.Types) > 0 {
+		guidelines = append(guidelines, "Commit types in use, most frequent first: "+styleNames(p.Types)+".")
+	}
+
+	switch {
+	case p.ScopedCommits*2 >= p.Commits:
+		guidelines = append(guidelines, "Most commits have a scope. Scopes in use: "+styleNames(p.Scopes)+".")
+	case len(p.Scopes) > 0:
+		guidelines = append(guidelines, "Scopes are used sparingly. Scopes in use: "+styleNames(p.Scopes)+".")
+	default:
+		guidelines = append(guidelines, "Commits do not use scopes.")
+	}
+
+	subject := "Subjects start with a capital letter"
+	if p.LowercaseSubjects {
+		subject = "Subjects start with a lowercase letter"
+	}
+	if p.SubjectPeriod {
+		guidelines = append(guidelines, subject+" and end with a period.")
+	} else {
+		guidelines = append(guidelines, subject+" and do not end with a period.")
+	}
+
+	switch p.BodyStyle {
+	case BodyStyleNone:
+		guidelines = append(guidelines, "Most commits have no body.")
+	case BodyStyleBullets:
+		guidelines = append(guidelines, "Bodies are bullet points.")
+	case BodyStyleParagraphs:
+		guidelines = append(guidelines, "Bodies are prose paragraphs.")
+	}
+
+	if len(p.Trailers) > 0 {
+		guidelines = append(guidelines, "Trailers in use: "+styleNames(p.Trailers)+".")
+	}
+	return guidelines
+}
+
+// styleNames lists the most frequent names, separated by commas.
+func styleNames(counts []StyleCount) string {
+	var names []string
+	for _, count := range counts[:min(len(counts), maxStyleNames)] {
+		names = append(names, count.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Stale reports whether the profile was learned longer than maxAge ago. A maxAge of 0 never expires.
+func (p StyleProfile) Stale(maxAge time.Duration) bool {
+	return maxAge > 0 && time.Since(p.LearnedAt) > maxAge
+}
+
+// LoadStyleProfile reads a style profile saved at path. A missing profile is an os.ErrNotExist error.
+func LoadStyleProfile(path string) (StyleProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StyleProfile{}, fmt.Errorf("could not read style profile: %w", err)
+	}
+
+	var profile StyleProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return StyleProfile{}, fmt.Errorf("could not decode style profile at %s: %w", path, err)
+	}
+	return profile, nil
+}
+
+// Save writes the profile to path, replacing any previous profile.
+func (p StyleProfile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode style profile: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create style profile directory at %s: %w", dir, err)
+	}
+	if err := writeFileAtomic(path, "profile-*.tmp", data); err != nil {
+		return fmt.Errorf("could not write style profile: %w", err)
+	}
+	return nil
+}
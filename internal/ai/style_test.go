@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var styleHistory = []string{
+	"feat(ai): add mock provider\n\n- Return canned responses in turn\n- Render a template otherwise\n\nRefs: #12",
+	"fix(git): handle renamed files\n\n- Parse the old path of renames",
+	"Merge branch 'main' into feature",
+	"feat(config): add usage budgets\n\n- Refuse calls over the budget",
+	"fixup! fix(git): handle renamed files",
+	"docs: describe the cache\n\nRefs: #10\nSigned-off-by: Test User <test@example.com>",
+}
+
+func TestLearnStyle(t *testing.T) {
+	profile, err := LearnStyle(styleHistory, 2)
+	if err != nil {
+		t.Fatalf("LearnStyle failed: %v", err)
+	}
+
+	if profile.Commits != 4 {
+		t.Errorf("expected 4 well-formed commits, got %d", profile.Commits)
+	}
+	expectedTypes := []StyleCount{{"feat", 2}, {"docs", 1}, {"fix", 1}}
+	if !reflect.DeepEqual(profile.Types, expectedTypes) {
+		t.Errorf("expected types %v, got %v", expectedTypes, profile.Types)
+	}
+	if profile.ScopedCommits != 3 || len(profile.Scopes) != 3 {
+		t.Errorf("expected 3 scoped commits with 3 scopes, got %d with %v", profile.ScopedCommits, profile.Scopes)
+	}
+	expectedTrailers := []StyleCount{{"Refs", 2}, {"Signed-off-by", 1}}
+	if !reflect.DeepEqual(profile.Trailers, expectedTrailers) {
+		t.Errorf("expected trailers %v, got %v", expectedTrailers, profile.Trailers)
+	}
+	if !profile.LowercaseSubjects || profile.SubjectPeriod {
+		t.Errorf("expected lowercase subjects without a period, got %+v", profile)
+	}
+	if profile.BodyStyle != BodyStyleBullets {
+		t.Errorf("expected body style %q, got %q", BodyStyleBullets, profile.BodyStyle)
+	}
+
+	// The newest commit of each type is picked in order of frequency.
+	expectedExamples := []string{styleHistory[0], styleHistory[5]}
+	if !reflect.DeepEqual(profile.Examples, expectedExamples) {
+		t.Errorf("expected examples %q, got %q", expectedExamples, profile.Examples)
+	}
+}
+
+func TestLearnStyle_NoConventionalCommits(t *testing.T) {
+	_, err := LearnStyle([]string{"Update README", "WIP"}, 3)
+	if !errors.Is(err, ErrNoConventionalCommits) {
+		t.Errorf("expected ErrNoConventionalCommits, got %v", err)
+	}
+}
+
+func TestBodyStyle(t *testing.T) {
+	testCases := []struct {
+		body     string
+		expected string
+	}{
+		{"", BodyStyleNone},
+		{"- One change\n- Another change", BodyStyleBullets},
+		{"This change explains itself\nin a paragraph.", BodyStyleParagraphs},
+	}
+	for _, tc := range testCases {
+		if got := bodyStyle(tc.body); got != tc.expected {
+			t.Errorf("bodyStyle(%q): expected %q, got %q", tc.body, tc.expected, got)
+		}
+	}
+}
+
+func TestStyleProfile_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "styles", "repo.json")
+	profile, err := LearnStyle(styleHistory, 3)
+	if err != nil {
+		t.Fatalf("LearnStyle failed: %v", err)
+	}
+	if err := profile.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadStyleProfile(path)
+	if err != nil {
+		t.Fatalf("LoadStyleProfile failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Guidelines(), profile.Guidelines()) || !reflect.DeepEqual(loaded.Examples, profile.Examples) {
+		t.Errorf("loaded profile differs from the saved one: %+v", loaded)
+	}
+	if loaded.Stale(time.Hour) {
+		t.Errorf("expected a fresh profile not to be stale")
+	}
+}
+
+func TestBuildPrompt_Style(t *testing.T) {
+	cfg := setupTestConfig()
+	data := NewPromptData(cfg, stagedDiff, "")
+	profile, err := LearnStyle(styleHistory, 1)
+	if err != nil {
+		t.Fatalf("LearnStyle failed: %v", err)
+	}
+	data.Style = profile
+
+	prompt, err := BuildPrompt(cfg, data)
+	if err != nil {
+		t.Fatalf("BuildPrompt failed: %v", err)
+	}
+
+	for _, expected := range []string{"**REPOSITORY CONVENTIONS:**", "- Commit types in use, most frequent first: feat, docs, fix.", styleHistory[0]} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("prompt missing %q", expected)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"CommitGen/internal/git"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/*
+Summarizer condenses a very large staged diff with a map-reduce approach: every group of files
+is summarized on its own, with a bounded number of parallel calls, and the summaries then take
+the place of the diff in the commit message prompt.
+*/
+type Summarizer struct {
+	cfg      *config.Config
+	provider LLMProvider
+}
+
+// NewSummarizer creates a Summarizer that uses provider for the summarization calls.
+func NewSummarizer(cfg *config.Config, provider LLMProvider) *Summarizer {
+	return &Summarizer{
+		cfg:      cfg,
+		provider: provider,
+	}
+}
+
+// NeedsSummary reports whether the estimated size of stagedDiff exceeds the summarization threshold.
+func (s Summarizer) NeedsSummary(stagedDiff string) bool {
+	threshold := s.cfg.AI.Summarize.Threshold
+	return threshold > 0 && estimateTokens(stagedDiff) > threshold
+}
+
+/*
+Summarize summarizes every group of files of stagedDiff and returns the joined summaries,
+headed by the paths of the files they describe, along with the tokens used. progress is
+called with the number of finished and total groups every time a summary completes; the calls
+may run concurrently and out of order.
+The first failing summary cancels the others and is returned as the error.
+*/
+func (s Summarizer) Summarize(ctx context.Context, stagedDiff string, progress func(done, total int)) (string, Usage, error) {
+	groups := groupFiles(git.ParseDiff(stagedDiff), s.cfg.AI.Summarize.GroupTokens)
+	if len(groups) == 0 {
+		return "", Usage{}, fmt.Errorf("could not find any files to summarize in the staged diff")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := make([]string, len(groups))
+	var usage Usage
+	var firstErr error
+	var done int
+	var mu sync.Mutex
+
+	semaphore := make(chan struct{}, max(s.cfg.AI.Summarize.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			resp, err := s.provider.Generate(ctx, GenerateRequest{
+				PromptData: PromptData{
+					StagedDiff: renderDiff(group, false, false),
+					Template:   s.cfg.Prompt.SummaryTemplate,
+				},
+			})
+
+			mu.Lock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("could not summarize %s: %w", groupPaths(group), err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+
+			summaries[i] = fmt.Sprintf("### %s\n%s\n", groupPaths(group), strings.TrimSpace(resp.Message))
+			usage.PromptTokens += resp.Usage.PromptTokens
+			usage.OutputTokens += resp.Usage.OutputTokens
+			usage.ThinkingTokens += resp.Usage.ThinkingTokens
+			usage.TotalTokens += resp.Usage.TotalTokens
+			done++
+			finished := done
+			mu.Unlock()
+
+			// The callback runs outside the lock, so a slow one does not hold up the other groups.
+			if progress != nil {
+				progress(finished, len(groups))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return "", usage, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", usage, err
+	}
+	return strings.Join(summaries, "\n"), usage, nil
+}
+
+/*
+groupFiles packs consecutive files into groups of about groupTokens estimated tokens, so that
+small files share a summarization call. A file larger than groupTokens forms its own group.
+*/
+func groupFiles(files []git.FileDiff, groupTokens int32) [][]git.FileDiff {
+	var groups [][]git.FileDiff
+	var current []git.FileDiff
+	var currentTokens int32
+
+	for _, file := range files {
+		tokens := estimateTokens(file.String())
+		if len(current) > 0 && currentTokens+tokens > groupTokens {
+			groups = append(groups, current)
+			current, currentTokens = nil, 0
+		}
+		current = append(current, file)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// groupPaths returns the comma-separated paths of a group of files.
+func groupPaths(group []git.FileDiff) string {
+	paths := make([]string, len(group))
+	for i, file := range group {
+		paths[i] = file.Path
+	}
+	return strings.Join(paths, ", ")
+}
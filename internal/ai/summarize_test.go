@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"CommitGen/internal/git"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+summaryProvider is an LLMProvider that answers summarization requests with the paths found in
+the diff it received, tracking the highest number of calls running at the same time.
+*/
+type summaryProvider struct {
+	mu       sync.Mutex
+	running  int
+	peak     int
+	failPath string
+}
+
+func (p *summaryProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	p.mu.Lock()
+	p.running++
+	p.peak = max(p.peak, p.running)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	files := git.ParseDiff(req.PromptData.StagedDiff)
+	if p.failPath != "" && files[0].Path == p.failPath {
+		return GenerateResponse{}, errors.New("summary failed")
+	}
+	return GenerateResponse{
+		Message: "- Changed " + groupPaths(files),
+		Usage:   Usage{TotalTokens: 10},
+	}, nil
+}
+
+func (p *summaryProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// largeTestDiff returns a staged diff touching count files of roughly 100 tokens each.
+func largeTestDiff(count int) string {
+	var diff strings.Builder
+	for i := range count {
+		path := fmt.Sprintf("gen/file%02d.go", i)
+		fmt.Fprintf(&diff, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -1 +1 @@\n", path, path, path, path)
+		diff.WriteString(strings.Repeat("+// generated line\n", 20))
+	}
+	return diff.String()
+}
+
+func TestGroupFiles(t *testing.T) {
+	files := git.ParseDiff(largeTestDiff(10))
+
+	groups := groupFiles(files, 250)
+	if len(groups) != 5 {
+		t.Fatalf("expected 10 files of ~100 tokens to form 5 groups of 2, got %d groups", len(groups))
+	}
+	if got := groupPaths(groups[0]); got != "gen/file00.go, gen/file01.go" {
+		t.Errorf("expected consecutive files to be grouped, got %q", got)
+	}
+
+	if groups := groupFiles(files, 10); len(groups) != 10 {
+		t.Errorf("expected files larger than the group size to be summarized alone, got %d groups", len(groups))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Summarize.GroupTokens = 250
+	cfg.AI.Summarize.Concurrency = 2
+	provider := &summaryProvider{}
+	summarizer := NewSummarizer(cfg, provider)
+
+	var (
+		mu       sync.Mutex
+		progress []int
+	)
+	summary, usage, err := summarizer.Summarize(context.Background(), largeTestDiff(10), func(done, total int) {
+		if total != 5 {
+			t.Errorf("expected 5 groups in total, got %d", total)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+
+	if provider.peak > 2 {
+		t.Errorf("expected at most 2 parallel calls, got %d", provider.peak)
+	}
+	slices.Sort(progress)
+	if !slices.Equal(progress, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected progress to be reported for every group, got %v", progress)
+	}
+	if usage.TotalTokens != 50 {
+		t.Errorf("expected usage to be summed over all calls, got %+v", usage)
+	}
+	if !strings.HasPrefix(summary, "### gen/file00.go, gen/file01.go\n- Changed gen/file00.go, gen/file01.go\n") {
+		t.Errorf("expected summaries in diff order headed by their paths, got:\n%s", summary)
+	}
+}
+
+func TestSummarize_Failure(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Summarize.GroupTokens = 250
+	summarizer := NewSummarizer(cfg, &summaryProvider{failPath: "gen/file04.go"})
+
+	_, _, err := summarizer.Summarize(context.Background(), largeTestDiff(10), nil)
+	if err == nil || !strings.Contains(err.Error(), "gen/file04.go") {
+		t.Errorf("expected the failing group to be reported, got %v", err)
+	}
+}
+
+func TestNeedsSummary(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Summarize.Threshold = 500
+	summarizer := NewSummarizer(cfg, &summaryProvider{})
+
+	if summarizer.NeedsSummary(largeTestDiff(2)) {
+		t.Error("expected a small diff not to be summarized")
+	}
+	if !summarizer.NeedsSummary(largeTestDiff(10)) {
+		t.Error("expected a large diff to be summarized")
+	}
+
+	cfg.AI.Summarize.Threshold = 0
+	if summarizer.NeedsSummary(largeTestDiff(10)) {
+		t.Error("expected a threshold of 0 to disable summarization")
+	}
+}
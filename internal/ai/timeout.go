@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"fmt"
+	"time"
+)
+
+/*
+TimeoutProvider implements the LLMProvider interface by bounding every request to the wrapped
+provider with a timeout. The request's context is cancelled once the timeout expires, so the
+in-flight HTTP request or command is aborted rather than left running in the background.
+*/
+type TimeoutProvider struct {
+	provider     LLMProvider
+	providerType config.ProviderType
+	timeout      time.Duration
+}
+
+// NewTimeoutProvider wraps provider, of type providerType, so that each request fails after timeout.
+func NewTimeoutProvider(provider LLMProvider, providerType config.ProviderType, timeout time.Duration) *TimeoutProvider {
+	return &TimeoutProvider{
+		provider:     provider,
+		providerType: providerType,
+		timeout:      timeout,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p TimeoutProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate forwards the request with a context that expires after the timeout. When the timeout
+is what stopped the request, the error names the provider and the timeout that expired; other
+errors, including cancellation of the caller's context, are returned unchanged.
+*/
+func (p TimeoutProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resp, err := p.provider.Generate(timeoutCtx, req)
+	if err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+		return resp, fmt.Errorf("%s timed out after %s, raise its timeout for slow models: %w", p.providerType, p.timeout, context.DeadlineExceeded)
+	}
+	return resp, err
+}
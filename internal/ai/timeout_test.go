@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingProvider waits until its request is cancelled, like a model that never answers.
+type blockingProvider struct{}
+
+func (blockingProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	<-ctx.Done()
+	return GenerateResponse{}, ctx.Err()
+}
+
+func (blockingProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func TestTimeoutGenerate_TimesOut(t *testing.T) {
+	provider := NewTimeoutProvider(blockingProvider{}, config.Ollama, 20*time.Millisecond)
+
+	_, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err == nil || !strings.Contains(err.Error(), "ollama timed out after 20ms") {
+		t.Errorf("expected a timeout error naming the provider, got %v", err)
+	}
+	if kind := ClassifyError(err); kind != ErrorKindTimeout {
+		t.Errorf("expected the error to classify as %v, got %v", ErrorKindTimeout, kind)
+	}
+}
+
+func TestTimeoutGenerate_Cancelled(t *testing.T) {
+	provider := NewTimeoutProvider(blockingProvider{}, config.Ollama, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := provider.Generate(ctx, newTestRequest(stagedDiff))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be returned unchanged, got %v", err)
+	}
+	if kind := ClassifyError(err); kind != ErrorKindCanceled {
+		t.Errorf("expected the error to classify as %v, got %v", ErrorKindCanceled, kind)
+	}
+}
@@ -2,7 +2,10 @@ package ai
 
 import (
 	"CommitGen/internal/config"
+	"CommitGen/internal/git"
 	"context"
+	"fmt"
+	"time"
 )
 
 // PromptData holds the necessary information to construct a commit message prompt for the LLM.
@@ -12,21 +15,260 @@ type PromptData struct {
 	DefaultCommitType     string
 	ForcedCommitType      string
 	ExistingCommitMessage string
+
+	// Scopes lists the project's conventional-commit scopes and Language the language of the message.
+	Scopes   []string
+	Language string
+
+	// RepoContext describes the repository, such as its branch and recent commit subjects.
+	git.RepoContext
+
+	// DiffStat summarizes the lines changed per file of the full staged diff.
+	DiffStat string
+
+	// Style holds the conventions learned from the repository's history, if a profile is available.
+	Style StyleProfile
+
+	// JSONOutput is set when the model must answer with a JSON CommitMessage instead of plain text.
+	JSONOutput bool
+
+	// TruncationNote describes what was left out of StagedDiff to fit the prompt budget, if anything.
+	TruncationNote string
+
+	// Summarized is set when StagedDiff holds per-file summaries of a very large diff instead of the diff itself.
+	Summarized bool
+
+	// Template overrides the configured prompt template, e.g. for the summarization steps.
+	Template string
+}
+
+/*
+GenerateOptions holds per-request generation settings. Nil fields fall back to the
+values configured for the provider.
+*/
+type GenerateOptions struct {
+	MaxTokens   *int32
+	Temperature *float32
+
+	// Candidates is the number of alternative messages to generate. Values below 2 request a single message.
+	Candidates int
+
+	// JSON requests a structured CommitMessage, using the provider's JSON mode where available.
+	JSON bool
+}
+
+/*
+GenerateRequest is the input of a single commit message generation.
+
+If Stream is set, providers that support streaming call it with the whole message generated
+so far every time more text arrives. The text may restart from scratch when a retry or a
+fallback provider takes over. Providers without streaming support ignore it.
+*/
+type GenerateRequest struct {
+	PromptData PromptData
+	Options    GenerateOptions
+	Stream     func(partial string)
+}
+
+// FinishReason is a provider-independent description of why the model stopped generating.
+type FinishReason string
+
+const (
+	FinishReasonStop       FinishReason = "stop"
+	FinishReasonMaxTokens  FinishReason = "max_tokens"
+	FinishReasonSafety     FinishReason = "safety"
+	FinishReasonRecitation FinishReason = "recitation"
+	FinishReasonOther      FinishReason = "other"
+)
+
+// Usage reports the number of tokens consumed by a generation, as far as the provider exposes them.
+type Usage struct {
+	PromptTokens   int32
+	OutputTokens   int32
+	ThinkingTokens int32
+	TotalTokens    int32
+}
+
+/*
+GenerateResponse is the result of a single commit message generation.
+When more than one candidate was requested, Candidates holds every generated message and
+Message is the first of them. In JSON mode, CommitMessages holds the parsed form of each of
+those messages in the same order. A FinishReason of FinishReasonMaxTokens marks a message
+that was cut off and may be incomplete.
+*/
+type GenerateResponse struct {
+	Message        string
+	Candidates     []string
+	CommitMessages []CommitMessage
+	FinishReason   FinishReason
+	Usage          Usage
+	Provider       config.ProviderType
+	Model          string
+
+	// Thoughts holds the summarized reasoning of thinking models that were asked to include it.
+	Thoughts string `json:"-"`
+
+	// Fallbacks lists the providers that failed before Provider produced the message.
+	Fallbacks []FallbackAttempt `json:"-"`
+
+	// Cached is set when the response was served from the local response cache.
+	Cached bool `json:"-"`
+}
+
+// Capabilities describes the optional features a provider implementation supports.
+type Capabilities struct {
+	Streaming          bool
+	JSONMode           bool
+	TokenCounting      bool
+	MultipleCandidates bool
 }
 
 // LLMProvider defines the interface that large language model (LLM) providers must implement to generate commit messages.
 type LLMProvider interface {
-	buildPrompt(stagedDiff string, existingCommitMessage string) (string, error)
-	Generate(ctx context.Context, stagedDiff string, existingCommitMessage string) (string, error)
+	Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error)
+	Capabilities() Capabilities
 }
 
-// GetProvider returns an initialized LLMProvider implementation based on the configured default AI provider.
+/*
+GetProvider returns an initialized LLMProvider implementation based on the configured default AI provider.
+If fallback providers are configured, the default provider is wrapped in a FallbackProvider chain,
+and if the response cache is enabled, the result is wrapped in a CacheProvider.
+*/
 func GetProvider(cfg *config.Config) (LLMProvider, error) {
-	switch cfg.AI.DefaultProvider {
+	switch cfg.AI.OutputFormat {
+	case "", config.OutputFormatText, config.OutputFormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported output_format: %q", cfg.AI.OutputFormat)
+	}
+
+	provider, err := newProviderChain(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.AI.Cache.Enabled {
+		return provider, nil
+	}
+
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewCacheProvider(cfg, provider, NewResponseCache(cacheDir, time.Duration(cfg.AI.Cache.TTL))), nil
+}
+
+/*
+newProviderChain returns the default provider, followed by the fallback providers if there are any.
+A provider that cannot be initialized, e.g. because its API key is missing, is skipped with a
+warning in cfg.Warnings, so the chain only fails when none of its providers can be used.
+*/
+func newProviderChain(cfg *config.Config) (LLMProvider, error) {
+	if len(cfg.AI.FallbackProviders) == 0 {
+		return newProvider(cfg, cfg.AI.DefaultProvider)
+	}
+
+	var providers []LLMProvider
+	var names []config.ProviderType
+	var firstErr error
+	seen := map[config.ProviderType]bool{}
+	for _, providerType := range append([]config.ProviderType{cfg.AI.DefaultProvider}, cfg.AI.FallbackProviders...) {
+		if seen[providerType] {
+			continue
+		}
+		seen[providerType] = true
+
+		provider, err := newProvider(cfg, providerType)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("Skipping provider %s, since it could not be initialized: %v", providerType, err))
+			continue
+		}
+		providers = append(providers, provider)
+		names = append(names, providerType)
+	}
+	if len(providers) == 0 {
+		return nil, firstErr
+	}
+	return NewFallbackProvider(providers, names)
+}
+
+/*
+newProvider returns an initialized LLMProvider implementation for the given provider type.
+It is wrapped in a BudgetProvider when a prompt token budget is set, in a TimeoutProvider when
+a timeout is set, in a UsageProvider when usage tracking is enabled, in a MaxTokensProvider,
+in a StructuredProvider when JSON output is configured, in a RetryProvider when retries are
+enabled and in a CandidatesProvider when the provider cannot generate several candidates in a
+single call.
+*/
+func newProvider(cfg *config.Config, providerType config.ProviderType) (LLMProvider, error) {
+	if maxTokens := cfg.AI.Providers[providerType].MaxTokens; maxTokens != nil && *maxTokens <= 0 {
+		return nil, fmt.Errorf("max_tokens of %s must be positive, got %d", providerType, *maxTokens)
+	}
+	if err := resolveAPIKey(cfg, providerType); err != nil {
+		return nil, err
+	}
+	provider, err := newBaseProvider(cfg, providerType)
+	if err != nil {
+		return nil, err
+	}
+	if maxTokens := cfg.AI.Providers[providerType].MaxPromptTokens; maxTokens != nil && *maxTokens > 0 {
+		provider, err = NewBudgetProvider(cfg, provider, *maxTokens)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if timeout := cfg.AI.Providers[providerType].Timeout; timeout > 0 {
+		provider = NewTimeoutProvider(provider, providerType, time.Duration(timeout))
+	}
+	if cfg.AI.Usage.Enabled {
+		provider, err = newUsageProvider(cfg, provider)
+		if err != nil {
+			return nil, err
+		}
+	}
+	provider = NewMaxTokensProvider(cfg, provider, providerType)
+	if cfg.AI.OutputFormat == config.OutputFormatJSON {
+		provider = NewStructuredProvider(provider)
+	}
+	if cfg.AI.Retry.MaxAttempts > 1 {
+		provider = NewRetryProvider(provider, cfg.AI.Retry)
+	}
+	if !provider.Capabilities().MultipleCandidates {
+		provider = NewCandidatesProvider(provider)
+	}
+	return provider, nil
+}
+
+// newUsageProvider wraps provider in a UsageProvider recording to the ledger in the state directory.
+func newUsageProvider(cfg *config.Config, provider LLMProvider) (LLMProvider, error) {
+	ledgerFile, err := config.UsageLedgerFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Calls made outside a repository are still recorded, just without one.
+	repo, _ := git.FindGitRoot()
+	return NewUsageProvider(cfg, provider, NewUsageLedger(ledgerFile), repo), nil
+}
+
+// newBaseProvider returns an initialized LLMProvider implementation for the given provider type.
+func newBaseProvider(cfg *config.Config, providerType config.ProviderType) (LLMProvider, error) {
+	switch providerType {
 	case config.Gemini:
 		return NewGeminiProvider(cfg)
 	case config.OpenAI:
-		return nil, nil // TODO: add OpenAI support
+		return NewOpenAIProvider(cfg)
+	case config.OpenAICompatible:
+		return NewOpenAICompatibleProvider(cfg)
+	case config.Anthropic:
+		return NewAnthropicProvider(cfg)
+	case config.Ollama:
+		return NewOllamaProvider(cfg)
+	case config.Exec:
+		return NewExecProvider(cfg)
+	case config.Mock:
+		return NewMockProvider(cfg)
 	}
-	return nil, nil
+	return nil, fmt.Errorf("unsupported AI provider: %q", providerType)
 }
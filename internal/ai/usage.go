@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when the daily token or cost budget has been used up.
+var ErrBudgetExceeded = errors.New("daily usage budget exceeded")
+
+// usageDayFormat is the layout of the day a usage record is attributed to, in local time.
+const usageDayFormat = "2006-01-02"
+
+// UsageRecord is a single provider call as stored in the usage ledger.
+type UsageRecord struct {
+	Time           time.Time           `json:"time"`
+	Repo           string              `json:"repo,omitempty"`
+	Provider       config.ProviderType `json:"provider"`
+	Model          string              `json:"model"`
+	PromptTokens   int32               `json:"prompt_tokens"`
+	OutputTokens   int32               `json:"output_tokens"`
+	ThinkingTokens int32               `json:"thinking_tokens,omitempty"`
+	TotalTokens    int32               `json:"total_tokens"`
+}
+
+// Cost returns the price of the call and whether a price is configured for its model.
+func (r UsageRecord) Cost(prices map[string]config.Price) (float64, bool) {
+	price, ok := prices[r.Model]
+	if !ok {
+		return 0, false
+	}
+	return price.Cost(int64(r.PromptTokens), int64(r.OutputTokens)+int64(r.ThinkingTokens)), true
+}
+
+/*
+UsageLedger is an append-only JSON Lines file of UsageRecords. Every record is written with a
+single append, so parallel calls and concurrent commitgen processes do not interleave lines.
+*/
+type UsageLedger struct {
+	path string
+}
+
+// NewUsageLedger creates a UsageLedger stored at path.
+func NewUsageLedger(path string) *UsageLedger {
+	return &UsageLedger{
+		path: path,
+	}
+}
+
+// Append adds record to the end of the ledger, creating the file if needed.
+func (l UsageLedger) Append(record UsageRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("could not encode usage record: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("could not create usage ledger directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open usage ledger at %s: %w", l.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write usage ledger at %s: %w", l.path, err)
+	}
+	return nil
+}
+
+/*
+Records returns every record of the ledger in the order they were added. A missing ledger has
+no records, and lines that cannot be decoded, such as a write cut short by a crash, are skipped.
+*/
+func (l UsageLedger) Records() ([]UsageRecord, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read usage ledger at %s: %w", l.path, err)
+	}
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// UsageSummary adds up the usage of one model in one repository on one day.
+type UsageSummary struct {
+	Day            string
+	Repo           string
+	Provider       config.ProviderType
+	Model          string
+	Calls          int
+	PromptTokens   int64
+	OutputTokens   int64
+	ThinkingTokens int64
+	TotalTokens    int64
+
+	// Cost only covers the calls whose model has a price, and Priced reports whether any did.
+	Cost   float64
+	Priced bool
+}
+
+// add accumulates record into the summary.
+func (s *UsageSummary) add(record UsageRecord, prices map[string]config.Price) {
+	s.Calls++
+	s.PromptTokens += int64(record.PromptTokens)
+	s.OutputTokens += int64(record.OutputTokens)
+	s.ThinkingTokens += int64(record.ThinkingTokens)
+	s.TotalTokens += int64(record.TotalTokens)
+	if cost, ok := record.Cost(prices); ok {
+		s.Cost += cost
+		s.Priced = true
+	}
+}
+
+/*
+SummarizeUsage groups the records made at or after since by day, repository and model,
+sorted in that order. The costs are computed with the given prices.
+*/
+func SummarizeUsage(records []UsageRecord, prices map[string]config.Price, since time.Time) []UsageSummary {
+	type groupKey struct {
+		day, repo string
+		provider  config.ProviderType
+		model     string
+	}
+
+	groups := map[groupKey]*UsageSummary{}
+	for _, record := range records {
+		if record.Time.Before(since) {
+			continue
+		}
+
+		key := groupKey{record.Time.Local().Format(usageDayFormat), record.Repo, record.Provider, record.Model}
+		summary, ok := groups[key]
+		if !ok {
+			summary = &UsageSummary{Day: key.day, Repo: key.repo, Provider: key.provider, Model: key.model}
+			groups[key] = summary
+		}
+		summary.add(record, prices)
+	}
+
+	summaries := make([]UsageSummary, 0, len(groups))
+	for _, summary := range groups {
+		summaries = append(summaries, *summary)
+	}
+	slices.SortFunc(summaries, func(a, b UsageSummary) int {
+		return cmp.Or(
+			strings.Compare(a.Day, b.Day),
+			strings.Compare(a.Repo, b.Repo),
+			strings.Compare(string(a.Provider), string(b.Provider)),
+			strings.Compare(a.Model, b.Model),
+		)
+	})
+	return summaries
+}
+
+// TotalUsage adds up the given summaries into one.
+func TotalUsage(summaries []UsageSummary) UsageSummary {
+	var total UsageSummary
+	for _, summary := range summaries {
+		total.Calls += summary.Calls
+		total.PromptTokens += summary.PromptTokens
+		total.OutputTokens += summary.OutputTokens
+		total.ThinkingTokens += summary.ThinkingTokens
+		total.TotalTokens += summary.TotalTokens
+		total.Cost += summary.Cost
+		total.Priced = total.Priced || summary.Priced
+	}
+	return total
+}
+
+// StartOfDay returns midnight of t's day in local time.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Local().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+/*
+UsageProvider implements the LLMProvider interface by recording the token usage of every
+successful call of the wrapped provider in a UsageLedger. Calls are refused with
+ErrBudgetExceeded once today's usage reached the configured daily token or cost budget.
+*/
+type UsageProvider struct {
+	cfg      *config.Config
+	provider LLMProvider
+	ledger   *UsageLedger
+	repo     string
+	now      func() time.Time
+}
+
+// NewUsageProvider wraps provider so that its usage is recorded in ledger under repo.
+func NewUsageProvider(cfg *config.Config, provider LLMProvider, ledger *UsageLedger, repo string) *UsageProvider {
+	return &UsageProvider{
+		cfg:      cfg,
+		provider: provider,
+		ledger:   ledger,
+		repo:     repo,
+		now:      time.Now,
+	}
+}
+
+// Capabilities reports the capabilities of the wrapped provider.
+func (p UsageProvider) Capabilities() Capabilities {
+	return p.provider.Capabilities()
+}
+
+/*
+Generate checks the daily budget, calls the wrapped provider and records the usage of its
+response, including responses that stopped early. Failing to record the usage never fails
+the generation.
+*/
+func (p UsageProvider) Generate(ctx context.Context, req GenerateRequest) (GenerateResponse, error) {
+	if err := p.checkBudget(); err != nil {
+		return GenerateResponse{}, err
+	}
+
+	resp, err := p.provider.Generate(ctx, req)
+	used := resp
+	if err != nil {
+		var stopped *StoppedError
+		if !errors.As(err, &stopped) {
+			return resp, err
+		}
+		used = stopped.Response
+	}
+
+	_ = p.ledger.Append(UsageRecord{
+		Time:           p.now(),
+		Repo:           p.repo,
+		Provider:       used.Provider,
+		Model:          used.Model,
+		PromptTokens:   used.Usage.PromptTokens,
+		OutputTokens:   used.Usage.OutputTokens,
+		ThinkingTokens: used.Usage.ThinkingTokens,
+		TotalTokens:    used.Usage.TotalTokens,
+	})
+	return resp, err
+}
+
+// checkBudget returns ErrBudgetExceeded if today's usage reached one of the daily budgets.
+func (p UsageProvider) checkBudget() error {
+	usageCfg := p.cfg.AI.Usage
+	if usageCfg.DailyTokenBudget <= 0 && usageCfg.DailyCostBudget <= 0 {
+		return nil
+	}
+
+	records, err := p.ledger.Records()
+	if err != nil {
+		return err
+	}
+	today := TotalUsage(SummarizeUsage(records, usageCfg.Prices, StartOfDay(p.now())))
+
+	if usageCfg.DailyTokenBudget > 0 && today.TotalTokens >= usageCfg.DailyTokenBudget {
+		return fmt.Errorf("%w: %d of %d tokens used today", ErrBudgetExceeded, today.TotalTokens, usageCfg.DailyTokenBudget)
+	}
+	if usageCfg.DailyCostBudget > 0 && today.Cost >= usageCfg.DailyCostBudget {
+		return fmt.Errorf("%w: %.4f of %.4f spent today", ErrBudgetExceeded, today.Cost, usageCfg.DailyCostBudget)
+	}
+	return nil
+}
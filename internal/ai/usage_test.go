@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"CommitGen/internal/config"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "usage.jsonl")
+	ledger := NewUsageLedger(path)
+
+	if records, err := ledger.Records(); err != nil || len(records) != 0 {
+		t.Fatalf("expected a missing ledger to have no records, got %v (err = %v)", records, err)
+	}
+
+	record := UsageRecord{Time: time.Now().UTC(), Repo: "/src/app", Provider: config.OpenAI, Model: "gpt-4o-mini", TotalTokens: 42}
+	if err := ledger.Append(record); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// A line cut short by a crash must not hide the records around it.
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString("{\"time\":\n")
+	file.Close()
+	ledger.Append(record)
+
+	records, err := ledger.Records()
+	if err != nil {
+		t.Fatalf("Records failed: %v", err)
+	}
+	if len(records) != 2 || !records[0].Time.Equal(record.Time) || records[1].TotalTokens != 42 {
+		t.Errorf("expected both records to be read back, got %+v", records)
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	today := StartOfDay(time.Now()).Add(12 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+	records := []UsageRecord{
+		{Time: today, Repo: "/src/app", Provider: config.OpenAI, Model: "gpt-4o-mini", PromptTokens: 1_000_000, OutputTokens: 500_000, TotalTokens: 1_500_000},
+		{Time: today, Repo: "/src/app", Provider: config.OpenAI, Model: "gpt-4o-mini", PromptTokens: 1_000_000, OutputTokens: 0, TotalTokens: 1_000_000},
+		{Time: today, Repo: "/src/app", Provider: config.Ollama, Model: "llama3.2", PromptTokens: 100, OutputTokens: 10, TotalTokens: 110},
+		{Time: yesterday, Repo: "/src/lib", Provider: config.OpenAI, Model: "gpt-4o-mini", PromptTokens: 10, TotalTokens: 10},
+	}
+	prices := map[string]config.Price{"gpt-4o-mini": {Input: 0.15, Output: 0.6}}
+
+	summaries := SummarizeUsage(records, prices, StartOfDay(yesterday))
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", summaries)
+	}
+	if summaries[0].Repo != "/src/lib" {
+		t.Errorf("expected the groups to be sorted by day, got %+v", summaries[0])
+	}
+
+	openAI := summaries[2]
+	if openAI.Calls != 2 || openAI.TotalTokens != 2_500_000 {
+		t.Errorf("expected the calls of one model to be added up, got %+v", openAI)
+	}
+	if !openAI.Priced || math.Abs(openAI.Cost-0.6) > 1e-9 {
+		t.Errorf("expected a cost of 0.6, got %v (priced = %v)", openAI.Cost, openAI.Priced)
+	}
+	if summaries[1].Priced {
+		t.Errorf("expected a model without a price to have no cost, got %+v", summaries[1])
+	}
+
+	if todayOnly := SummarizeUsage(records, prices, StartOfDay(today)); len(todayOnly) != 2 {
+		t.Errorf("expected records before since to be left out, got %+v", todayOnly)
+	}
+}
+
+func TestUsageProvider(t *testing.T) {
+	cfg := setupTestConfig()
+	cfg.AI.Providers[config.Mock] = config.ProviderConfig{Model: "mock"}
+	mock, _ := NewMockProvider(cfg)
+	ledger := NewUsageLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+	provider := NewUsageProvider(cfg, mock, ledger, "/src/app")
+
+	resp, err := provider.Generate(context.Background(), newTestRequest(stagedDiff))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	records, _ := ledger.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected the call to be recorded, got %+v", records)
+	}
+	record := records[0]
+	if record.Repo != "/src/app" || record.Provider != config.Mock || record.Model != "mock" || record.TotalTokens != resp.Usage.TotalTokens {
+		t.Errorf("unexpected usage record %+v for usage %+v", record, resp.Usage)
+	}
+
+	// The recorded call alone uses up the budget, so the next one is refused.
+	cfg.AI.Usage.DailyTokenBudget = int64(resp.Usage.TotalTokens)
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	cfg.AI.Usage.DailyTokenBudget = 0
+	cfg.AI.Usage.DailyCostBudget = 0.01
+	cfg.AI.Usage.Prices = map[string]config.Price{"mock": {Input: 10_000, Output: 10_000}}
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected the cost budget to be enforced, got %v", err)
+	}
+
+	provider.now = func() time.Time { return time.Now().AddDate(0, 0, 1) }
+	if _, err := provider.Generate(context.Background(), newTestRequest(stagedDiff)); err != nil {
+		t.Errorf("expected the budget to reset the next day, got %v", err)
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 /*
@@ -118,6 +121,108 @@ commit_username = "Test User"
 		}
 	})
 
+	t.Run("Retry durations are parsed from strings", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+		configDir := filepath.Join(tempDir, "commitgen")
+		os.MkdirAll(configDir, 0755)
+		configFile := filepath.Join(configDir, "config.toml")
+
+		retryConfigContent := `
+[ai.retry]
+  max_attempts = 5
+  initial_backoff = "250ms"
+`
+		if err := os.WriteFile(configFile, []byte(retryConfigContent), 0644); err != nil {
+			t.Fatalf("failed to write retry config file: %v", err)
+		}
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() failed: %v", err)
+		}
+
+		if cfg.AI.Retry.MaxAttempts != 5 {
+			t.Errorf("expected ai.retry.max_attempts to be 5, got %d", cfg.AI.Retry.MaxAttempts)
+		}
+		if time.Duration(cfg.AI.Retry.InitialBackoff) != 250*time.Millisecond {
+			t.Errorf("expected ai.retry.initial_backoff to be 250ms, got %s", time.Duration(cfg.AI.Retry.InitialBackoff))
+		}
+		if time.Duration(cfg.AI.Retry.MaxBackoff) != time.Minute {
+			t.Errorf("expected ai.retry.max_backoff to keep its default of 1m, got %s", time.Duration(cfg.AI.Retry.MaxBackoff))
+		}
+	})
+
+	t.Run("Provider timeouts fall back to the global timeout", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+		configDir := filepath.Join(tempDir, "commitgen")
+		os.MkdirAll(configDir, 0755)
+		configFile := filepath.Join(configDir, "config.toml")
+
+		timeoutConfigContent := `
+[ai]
+  timeout = "45s"
+
+[ai.providers.exec]
+  command = ["cat"]
+  timeout = "10m"
+`
+		if err := os.WriteFile(configFile, []byte(timeoutConfigContent), 0644); err != nil {
+			t.Fatalf("failed to write timeout config file: %v", err)
+		}
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() failed: %v", err)
+		}
+
+		if timeout := time.Duration(cfg.AI.Providers[Exec].Timeout); timeout != 10*time.Minute {
+			t.Errorf("expected the exec timeout to be 10m, got %s", timeout)
+		}
+		if timeout := time.Duration(cfg.AI.Providers[OpenAI].Timeout); timeout != 45*time.Second {
+			t.Errorf("expected the openai timeout to inherit 45s, got %s", timeout)
+		}
+	})
+
+	t.Run("Config file with a plaintext API key is made private", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Windows does not use permission bits")
+		}
+		tempDir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+		configDir := filepath.Join(tempDir, "commitgen")
+		os.MkdirAll(configDir, 0755)
+		configFile := filepath.Join(configDir, "config.toml")
+
+		keyConfigContent := `
+[ai.providers.gemini]
+  api_key = "plaintext-key"
+`
+		if err := os.WriteFile(configFile, []byte(keyConfigContent), 0644); err != nil {
+			t.Fatalf("failed to write key config file: %v", err)
+		}
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() failed: %v", err)
+		}
+
+		info, err := os.Stat(configFile)
+		if err != nil {
+			t.Fatalf("failed to stat config file: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expected permissions 0600, got %04o", info.Mode().Perm())
+		}
+		if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "plaintext API key") {
+			t.Errorf("expected a warning about the plaintext API key, got %q", cfg.Warnings)
+		}
+	})
+
 	t.Run("Malformed config file returns an error", func(t *testing.T) {
 		tempDir := t.TempDir()
 		t.Setenv("XDG_CONFIG_HOME", tempDir)
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is written to and read from TOML as a string such as "90s" or "2m".
+type Duration time.Duration
+
+// MarshalText encodes the duration using time.Duration's string format.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses a duration string such as "30s" or "1m30s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", string(text), err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
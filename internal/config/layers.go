@@ -0,0 +1,231 @@
+package config
+
+import (
+	"CommitGen/internal/git"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// OriginDefault is the origin of the values that no config file sets.
+const OriginDefault = "default"
+
+// repoConfigFileNames are the names of the repository config at the git root, in order of precedence.
+var repoConfigFileNames = []string{".commitgen.toml", filepath.Join(".commitgen", "config.toml")}
+
+/*
+repoDisallowedKeys can only be set in the user config, where * matches any provider. A repository
+config comes with the code of a project, so it must not run commands, such as an editor or a key
+command, or redirect the user's API keys and diffs to other endpoints.
+*/
+var repoDisallowedKeys = []string{
+	"editor", "commit_username", "commit_email",
+	"ai.providers.*.api_key", "ai.providers.*.api_key_env", "ai.providers.*.api_key_cmd", "ai.providers.*.api_key_keyring",
+	"ai.providers.*.base_url", "ai.providers.*.headers", "ai.providers.*.proxy",
+	"ai.providers.*.ca_file", "ai.providers.*.client_cert", "ai.providers.*.client_key", "ai.providers.*.insecure_skip_verify",
+	"ai.providers.*.project", "ai.providers.*.credentials_file", "ai.providers.*.command",
+}
+
+// providerMapType is the type of ai.providers, whose tables are merged provider by provider.
+var providerMapType = reflect.TypeFor[ProviderMap]()
+
+// bareKeyRegexp matches the TOML keys that can be written without quotes.
+var bareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Setting is a single effective configuration value, identified by its dotted TOML key.
+type Setting struct {
+	Key    string
+	Value  any
+	Origin string
+}
+
+// findRepoConfig returns the path of the repository config of the current git repository, if it has one.
+func findRepoConfig() (string, bool) {
+	root, err := git.FindGitRoot()
+	if err != nil {
+		return "", false
+	}
+
+	for _, name := range repoConfigFileNames {
+		path := filepath.Join(root, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+/*
+loadLayer merges the TOML file at path over the configuration, field by field, and records the
+file as the origin of every value it sets. Maps the file sets, such as prompt.commit_types, are
+replaced rather than merged, except for ai.providers. Keys listed in disallowed are ignored with
+a warning.
+*/
+func (c *Config) loadLayer(path string, disallowed []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file at %s: %w", path, err)
+	}
+
+	var values map[string]any
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	var removed bool
+	for _, pattern := range disallowed {
+		for _, key := range removeKey(values, strings.Split(pattern, "."), "") {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s may not set %s, so it is ignored. Set it in the user config instead.", path, key))
+			removed = true
+		}
+	}
+	if removed {
+		if data, err = toml.Marshal(values); err != nil {
+			return fmt.Errorf("could not encode config file %s: %w", path, err)
+		}
+	}
+
+	/*
+		Unmarshal the TOML data into the existing config struct.
+		Fields present in the file will override the values of the previous layers.
+		Decoding into a map adds to its entries, so the maps set by the file are emptied first.
+	*/
+	clearMaps(reflect.ValueOf(c).Elem(), values)
+	if err := toml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	if c.Origins == nil {
+		c.Origins = map[string]string{}
+	}
+	for key := range flattenValues("", values, map[string]any{}) {
+		c.Origins[key] = path
+	}
+	return nil
+}
+
+/*
+removeKey deletes the values at the nested key path from values, where * matches any key, and
+returns the dotted keys of the deleted values. prefix is the dotted key of values.
+*/
+func removeKey(values map[string]any, path []string, prefix string) []string {
+	names := []string{path[0]}
+	if path[0] == "*" {
+		names = slices.Sorted(maps.Keys(values))
+	}
+
+	var removed []string
+	for _, name := range names {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if len(path) == 1 {
+			delete(values, name)
+			removed = append(removed, key)
+			continue
+		}
+		if table, ok := value.(map[string]any); ok {
+			removed = append(removed, removeKey(table, path[1:], key)...)
+		}
+	}
+	return removed
+}
+
+/*
+clearMaps empties the map fields of the struct v that the TOML tables in values set, so that
+decoding values replaces them. The providers of ai.providers are kept, and only their own maps
+are emptied.
+*/
+func clearMaps(v reflect.Value, values map[string]any) {
+	for i := range v.NumField() {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ",")
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		table, _ := value.(map[string]any)
+
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			clearMaps(field, table)
+		case field.Type() == providerMapType:
+			for providerName, providerValues := range table {
+				providerType := reflect.ValueOf(ProviderType(providerName))
+				providerCfg := field.MapIndex(providerType)
+				if !providerCfg.IsValid() {
+					continue
+				}
+				providerValues, _ := providerValues.(map[string]any)
+				// Map values cannot be modified in place, so the cleared copy is stored back.
+				cleared := reflect.New(providerCfg.Type()).Elem()
+				cleared.Set(providerCfg)
+				clearMaps(cleared, providerValues)
+				field.SetMapIndex(providerType, cleared)
+			}
+		case field.Kind() == reflect.Map:
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+	}
+}
+
+// flattenValues adds every leaf of the nested TOML tables in values to out, under its dotted key.
+func flattenValues(prefix string, values map[string]any, out map[string]any) map[string]any {
+	for name, value := range values {
+		if !bareKeyRegexp.MatchString(name) {
+			name = strconv.Quote(name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if table, ok := value.(map[string]any); ok {
+			flattenValues(key, table, out)
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
+
+/*
+Settings returns every effective value of the configuration, sorted by key, along with the
+config file that set it or OriginDefault.
+*/
+func (c *Config) Settings() ([]Setting, error) {
+	data, err := toml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode config: %w", err)
+	}
+	var values map[string]any
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("could not decode config: %w", err)
+	}
+
+	var settings []Setting
+	for key, value := range flattenValues("", values, map[string]any{}) {
+		origin, ok := c.Origins[key]
+		if !ok {
+			origin = OriginDefault
+		}
+		settings = append(settings, Setting{Key: key, Value: value, Origin: origin})
+	}
+	slices.SortFunc(settings, func(a, b Setting) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return settings, nil
+}
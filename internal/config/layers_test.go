@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+setupLayeredConfig writes the given user config and a repository config at repoConfigPath,
+relative to a new git repository that becomes the working directory.
+*/
+func setupLayeredConfig(t *testing.T, userConfig, repoConfigPath, repoConfig string) (string, string) {
+	t.Helper()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userConfigFile := filepath.Join(configHome, "commitgen", "config.toml")
+	os.MkdirAll(filepath.Dir(userConfigFile), 0755)
+	if err := os.WriteFile(userConfigFile, []byte(userConfig), 0600); err != nil {
+		t.Fatalf("failed to write user config file: %v", err)
+	}
+
+	repoPath := t.TempDir()
+	os.Mkdir(filepath.Join(repoPath, ".git"), 0755)
+	repoConfigFile := filepath.Join(repoPath, repoConfigPath)
+	os.MkdirAll(filepath.Dir(repoConfigFile), 0755)
+	if err := os.WriteFile(repoConfigFile, []byte(repoConfig), 0644); err != nil {
+		t.Fatalf("failed to write repository config file: %v", err)
+	}
+
+	// Work from a subdirectory, like commitgen does when run deep inside a repository.
+	subDir := filepath.Join(repoPath, "internal", "pkg")
+	os.MkdirAll(subDir, 0755)
+	t.Chdir(subDir)
+	return userConfigFile, repoConfigFile
+}
+
+func TestLoadConfig_RepoConfig(t *testing.T) {
+	userConfig := `
+default_type = "feat"
+
+[ai]
+  max_tokens = 999
+
+[ai.providers.gemini]
+  api_key = "user-key"
+`
+	repoConfig := `
+editor = "rm -rf"
+
+[ai]
+  candidates = 3
+
+[ai.providers.gemini]
+  model = "gemini-repo"
+  base_url = "https://example.invalid"
+
+[prompt]
+  scopes = ["ai", "git"]
+  language = "German"
+
+[prompt.commit_types]
+  perf = "A performance improvement"
+`
+
+	for _, repoConfigPath := range repoConfigFileNames {
+		t.Run(repoConfigPath, func(t *testing.T) {
+			userConfigFile, repoConfigFile := setupLayeredConfig(t, userConfig, repoConfigPath, repoConfig)
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+
+			// Values of the user config are kept unless the repository config sets them.
+			if cfg.DefaultType != "feat" || cfg.AI.MaxTokens != 999 || cfg.AI.Candidates != 3 {
+				t.Errorf("expected values of both configs, got %+v", cfg.AI)
+			}
+			if !reflect.DeepEqual(cfg.Prompt.Scopes, []string{"ai", "git"}) || cfg.Prompt.Language != "German" {
+				t.Errorf("expected the scopes and language of the repository config, got %q and %q", cfg.Prompt.Scopes, cfg.Prompt.Language)
+			}
+			// Maps are replaced, so a repository can narrow the commit types.
+			if !reflect.DeepEqual(cfg.Prompt.CommitTypes, map[string]string{"perf": "A performance improvement"}) {
+				t.Errorf("expected the commit types of the repository config only, got %v", cfg.Prompt.CommitTypes)
+			}
+
+			// Providers are merged, and the settings that run commands or receive the API keys are ignored.
+			gemini := cfg.AI.Providers[Gemini]
+			if gemini.Model != "gemini-repo" || gemini.APIKey != "user-key" {
+				t.Errorf("expected the repository model and the user API key, got %q and %q", gemini.Model, gemini.APIKey)
+			}
+			if cfg.Editor == "rm -rf" || gemini.BaseURL != "" {
+				t.Errorf("expected editor and base_url of the repository config to be ignored")
+			}
+			if len(cfg.Warnings) != 2 || !strings.Contains(cfg.Warnings[0], "may not set editor") ||
+				!strings.Contains(cfg.Warnings[1], "may not set ai.providers.gemini.base_url") {
+				t.Errorf("expected warnings about the ignored keys, got %q", cfg.Warnings)
+			}
+
+			origins := map[string]string{}
+			settings, err := cfg.Settings()
+			if err != nil {
+				t.Fatalf("Settings() failed: %v", err)
+			}
+			for _, setting := range settings {
+				origins[setting.Key] = setting.Origin
+			}
+			expectedOrigins := map[string]string{
+				"default_type":              userConfigFile,
+				"ai.max_tokens":             userConfigFile,
+				"ai.candidates":             repoConfigFile,
+				"prompt.commit_types.perf":  repoConfigFile,
+				"ai.providers.gemini.model": repoConfigFile,
+				"ai.temperature":            OriginDefault,
+
+				// Providers inherit the global settings they do not set themselves.
+				"ai.providers.gemini.max_tokens":  userConfigFile + " (inherited from ai.max_tokens)",
+				"ai.providers.gemini.temperature": OriginDefault,
+			}
+			for key, expected := range expectedOrigins {
+				if origins[key] != expected {
+					t.Errorf("expected the origin of %s to be %q, got %q", key, expected, origins[key])
+				}
+			}
+		})
+	}
+}
+
+func TestFlattenValues(t *testing.T) {
+	values := map[string]any{
+		"ai": map[string]any{
+			"max_tokens": int64(10),
+			"usage": map[string]any{
+				"prices": map[string]any{"gpt-4.1": map[string]any{"input": 2.0}},
+			},
+		},
+		"default_type": "feat",
+	}
+
+	expected := map[string]any{
+		"ai.max_tokens":                   int64(10),
+		`ai.usage.prices."gpt-4.1".input`: 2.0,
+		"default_type":                    "feat",
+	}
+	if flattened := flattenValues("", values, map[string]any{}); !reflect.DeepEqual(flattened, expected) {
+		t.Errorf("expected %v, got %v", expected, flattened)
+	}
+}
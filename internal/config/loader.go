@@ -1,30 +1,59 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
 /*
 SetupLocalProviderOverrides iterates through AI providers and sets default global values
-for MaxTokens and Temperature if they are not explicitly defined in the provider's configuration.
+for MaxTokens, Temperature, MaxPromptTokens and Timeout if they are not explicitly defined
+in the provider's configuration.
 */
 func (cfg *Config) SetupLocalProviderOverrides() {
 	for providerType, providerCfg := range cfg.AI.Providers {
 		if providerCfg.MaxTokens == nil {
 			providerCfg.MaxTokens = &cfg.AI.MaxTokens
+			cfg.inheritOrigin(providerType, "max_tokens")
 		}
 
 		if providerCfg.Temperature == nil {
 			providerCfg.Temperature = &cfg.AI.Temperature
+			cfg.inheritOrigin(providerType, "temperature")
+		}
+
+		if providerCfg.MaxPromptTokens == nil {
+			providerCfg.MaxPromptTokens = &cfg.AI.MaxPromptTokens
+			cfg.inheritOrigin(providerType, "max_prompt_tokens")
+		}
+
+		if providerCfg.Timeout == 0 {
+			providerCfg.Timeout = cfg.AI.Timeout
+			cfg.inheritOrigin(providerType, "timeout")
 		}
 		cfg.AI.Providers[providerType] = providerCfg
 	}
 }
 
+/*
+inheritOrigin records that the provider's key was copied from the global ai.<key> setting, so
+'config show -origin' names the file that set the global value. Values inherited from a default
+global setting are defaults themselves and need no origin.
+*/
+func (cfg *Config) inheritOrigin(providerType ProviderType, key string) {
+	origin, ok := cfg.Origins["ai."+key]
+	if !ok {
+		return
+	}
+	cfg.Origins[fmt.Sprintf("ai.providers.%s.%s", providerType, key)] = fmt.Sprintf("%s (inherited from ai.%s)", origin, key)
+}
+
 /*
 getConfigDir determines the absolute path for the application's configuration directory
 and file based on XDG Base Directory Specification.
@@ -46,6 +75,77 @@ func getConfigDir() (string, error) {
 	return configFile, nil
 }
 
+/*
+StateDir returns the directory where commitgen keeps its log and cache, creating it if needed.
+
+It first checks for the XDG_STATE_HOME environment variable. If the variable
+doesn't exist, it falls back based on the operating system:
+
+- Linux/macOS Fallback: ~/.local/state/commitgen
+
+- Windows Fallback:     %LOCALAPPDATA%\commitgen
+*/
+func StateDir() (string, error) {
+	var stateHome string
+
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		stateHome = xdgState
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not find user home directory: %w", err)
+		}
+
+		if runtime.GOOS == "windows" {
+			stateHome = os.Getenv("LOCALAPPDATA")
+			if stateHome == "" {
+				stateHome = filepath.Join(homeDir, "AppData", "Local")
+			}
+		} else {
+			stateHome = filepath.Join(homeDir, ".local", "state")
+		}
+	}
+
+	stateDir := filepath.Join(stateHome, "commitgen")
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return "", fmt.Errorf("could not create state directory at %s: %w", stateDir, err)
+	}
+	return stateDir, nil
+}
+
+// CacheDir returns the directory of the response cache inside the state directory.
+func CacheDir() (string, error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "cache"), nil
+}
+
+// UsageLedgerFile returns the path of the token usage ledger inside the state directory.
+func UsageLedgerFile() (string, error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "usage.jsonl"), nil
+}
+
+/*
+StyleProfileFile returns the path of the style profile of the repository at repoRoot inside the
+state directory. Profiles are named after the repository and a hash of its path, so repositories
+with the same name do not share a profile.
+*/
+func StyleProfileFile(repoRoot string) (string, error) {
+	stateDir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(repoRoot))
+	name := fmt.Sprintf("%s-%s.json", filepath.Base(repoRoot), hex.EncodeToString(hash[:6]))
+	return filepath.Join(stateDir, "styles", name), nil
+}
+
 // GenerateConfig creates the default config object and writes to the default config location.
 func GenerateConfig() error {
 	configFile, err := getConfigDir()
@@ -59,16 +159,41 @@ func GenerateConfig() error {
 		return fmt.Errorf("could not marshal default config to TOML: %w", err)
 	}
 
-	if err := os.WriteFile(configFile, data, 0644); err != nil {
+	// The file may hold API keys, so it is only readable by its owner.
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
 		return fmt.Errorf("could not write default config file: %w", err)
 	}
 	return nil
 }
 
+/*
+restrictPermissions makes a config file holding a plaintext API key readable by its owner only,
+since other users of a shared host could read the key otherwise. It returns a warning describing
+the change, or why it could not be made, and nothing if the file was already private.
+*/
+func restrictPermissions(configFile string, cfg *Config) string {
+	// Windows does not use permission bits, so there is nothing to check.
+	if runtime.GOOS == "windows" || !cfg.hasPlaintextAPIKey() {
+		return ""
+	}
+
+	info, err := os.Stat(configFile)
+	if err != nil || info.Mode().Perm()&0077 == 0 {
+		return ""
+	}
+
+	mode := info.Mode().Perm() & 0700
+	if err := os.Chmod(configFile, mode); err != nil {
+		return fmt.Sprintf("%s contains a plaintext API key and is readable by other users, but its permissions could not be restricted: %v", configFile, err)
+	}
+	return fmt.Sprintf("%s contains a plaintext API key and was readable by other users, so its permissions were restricted to %04o", configFile, mode)
+}
+
 /*
 LoadConfig attempts to find and load the application's configuration file.
 If the file does not exist, it generates a default configuration.
 It then parses the configuration, merging it with default settings,
+layers the repository config of the current git repository on top,
 and applies local provider overrides.
 */
 func LoadConfig() (*Config, error) {
@@ -77,28 +202,26 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	cfg := NewDefaultConfig()
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		if err := GenerateConfig(); err != nil {
 			return nil, err
 		}
-		cfg := NewDefaultConfig()
-		cfg.SetupLocalProviderOverrides()
-		return cfg, nil
-	}
-
-	// --- File exists: Load it and merge with defaults ---
-	cfg := NewDefaultConfig()
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		return nil, fmt.Errorf("could not read config file at %s: %w", configFile, err)
+	} else {
+		// --- File exists: Load it and merge with defaults ---
+		if err := cfg.loadLayer(configFile, nil); err != nil {
+			return nil, err
+		}
+		cfg.migrateLegacyPrompt()
+		if warning := restrictPermissions(configFile, cfg); warning != "" {
+			cfg.Warnings = append(cfg.Warnings, warning)
+		}
 	}
 
-	/*
-		Unmarshal the TOML data into the defaultConfig struct.
-		Fields present in the file will override the default values.
-	*/
-	if err := toml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("could not parse config file: %w", err)
+	if repoConfigFile, ok := findRepoConfig(); ok {
+		if err := cfg.loadLayer(repoConfigFile, repoDisallowedKeys); err != nil {
+			return nil, err
+		}
 	}
 	cfg.SetupLocalProviderOverrides()
 	return cfg, nil
@@ -0,0 +1,87 @@
+package config
+
+import "strings"
+
+/*
+legacyDefaultTemplate is the default prompt.template of the versions that sent the whole prompt
+as a single message. It was written to every generated config.toml, so many user configs still
+hold it even though it repeats the instructions of the current system template.
+*/
+const legacyDefaultTemplate = `You are an expert at writing conventional commit messages.
+
+**INSTRUCTIONS:**
+- Your primary task is to generate a Git commit message based on the provided staged diff.
+- If an existing commit message is provided, amend it based on the new diff and instructions.
+{{if .ForcedCommitType}}
+- You MUST use the commit type: {{.ForcedCommitType}}
+{{else}}
+- Choose the best commit type from the provided list.
+- If you are unsure which type to use, default to: {{.DefaultCommitType}}
+{{end}}
+
+**GUIDELINES:**
+- Focus on explaining the 'why' of the changes, not just the 'what'.
+- Aim for clarity, conciseness, and descriptiveness in the summary and body.
+- Consider the broader context of the changes (e.g., feature, bug fix, refactor).
+
+**RULES:**
+- The commit message must be written in English.
+- Do not include any conversational text, explanations, or meta-commentary outside of the commit message itself.
+- Do not include sensitive information or personal opinions.
+- Ensure the message accurately reflects the changes in the staged diff.
+
+**FORMAT:**
+{commit_type}{commit_scope (optional)}: {commit_summary}
+
+{commit_body}
+
+- The subject line (first line) must be 50-72 characters or less.
+- The commit summary should start with a capital letter.
+- Separate the subject line from the body with a blank line.
+- Wrap body lines at 72 characters.
+- The body should be a collection of bullet points explaining the details of the commit.
+- Bullet points should uses dashes and not asterisks.
+- The scope is optional and should be surrounded by parentheses.
+
+{{if .ExistingCommitMessage}}
+**EXISTING COMMIT MESSAGE:**{{.ExistingCommitMessage}}
+{{end}}
+
+**STAGED DIFF:**
+{{.StagedDiff}}
+
+
+{{if not .ForcedCommitType}}
+**COMMIT TYPES:**
+{{range $type, $description := .CommitTypes}}
+- {{$type}}: {{$description}}
+{{end}}
+{{end}}
+
+The final output should be only the raw commit message, without any markdown formatting.`
+
+/*
+migrateLegacyPrompt updates the prompt of a user config written before the prompt was split into
+a system and a user template, which is one that sets prompt.template but not prompt.system_template.
+The legacy default template is treated as unset, so the current defaults apply. A custom template
+holds its own instructions, so it is still sent alone rather than after the default system template.
+*/
+func (c *Config) migrateLegacyPrompt() {
+	origin, hasTemplate := c.Origins["prompt.template"]
+	if _, hasSystemTemplate := c.Origins["prompt.system_template"]; !hasTemplate || hasSystemTemplate {
+		return
+	}
+
+	if normalizeTemplate(c.Prompt.Template) == normalizeTemplate(legacyDefaultTemplate) {
+		c.Prompt.Template = NewDefaultPromptConfig().Template
+		delete(c.Origins, "prompt.template")
+		return
+	}
+	c.Prompt.SystemTemplate = ""
+	c.Origins["prompt.system_template"] = origin
+}
+
+// normalizeTemplate ignores the line endings and surrounding whitespace of a template, which editors tend to change.
+func normalizeTemplate(template string) string {
+	return strings.TrimSpace(strings.ReplaceAll(template, "\r\n", "\n"))
+}
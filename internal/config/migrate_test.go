@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_LegacyPrompt(t *testing.T) {
+	// baseline_config.toml was generated by 'commitgen generate-config' before the system template existed.
+	baseline, err := os.ReadFile(filepath.Join("testdata", "baseline_config.toml"))
+	if err != nil {
+		t.Fatalf("failed to read baseline config: %v", err)
+	}
+	defaults := NewDefaultPromptConfig()
+	customTemplate := "Write a commit message for:\n{{.StagedDiff}}"
+
+	testCases := []struct {
+		name                   string
+		config                 string
+		expectedTemplate       string
+		expectedSystemTemplate string
+	}{
+		{
+			name:                   "Legacy default template",
+			config:                 string(baseline),
+			expectedTemplate:       defaults.Template,
+			expectedSystemTemplate: defaults.SystemTemplate,
+		},
+		{
+			name:                   "Legacy default template with CRLF line endings",
+			config:                 strings.ReplaceAll(string(baseline), "\n", "\r\n"),
+			expectedTemplate:       defaults.Template,
+			expectedSystemTemplate: defaults.SystemTemplate,
+		},
+		{
+			name:                   "Custom template without system template",
+			config:                 "[prompt]\ntemplate = '''" + customTemplate + "'''\n",
+			expectedTemplate:       customTemplate,
+			expectedSystemTemplate: "",
+		},
+		{
+			name:                   "Custom template with system template",
+			config:                 "[prompt]\nsystem_template = 'Be brief.'\ntemplate = '''" + customTemplate + "'''\n",
+			expectedTemplate:       customTemplate,
+			expectedSystemTemplate: "Be brief.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", tempDir)
+			configFile := filepath.Join(tempDir, "commitgen", "config.toml")
+			os.MkdirAll(filepath.Dir(configFile), 0755)
+			if err := os.WriteFile(configFile, []byte(tc.config), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig() failed: %v", err)
+			}
+			if cfg.Prompt.Template != tc.expectedTemplate {
+				t.Errorf("expected template %q, got %q", tc.expectedTemplate, cfg.Prompt.Template)
+			}
+			if cfg.Prompt.SystemTemplate != tc.expectedSystemTemplate {
+				t.Errorf("expected system template %q, got %q", tc.expectedSystemTemplate, cfg.Prompt.SystemTemplate)
+			}
+		})
+	}
+}
@@ -7,8 +7,9 @@ func (c *Config) OverrideFromFlags(
 	apiKey,
 	model *string,
 	temperature *float64,
-	maxTokens *int,
-
+	maxTokens,
+	candidates *int,
+	noCache *bool,
 ) {
 	if *commitType != "" {
 		c.ForcedCommitType = *commitType
@@ -17,6 +18,7 @@ func (c *Config) OverrideFromFlags(
 	var targetProvider ProviderType
 	if *provider != "" {
 		targetProvider = ProviderType(*provider)
+		c.AI.DefaultProvider = targetProvider
 	} else {
 		targetProvider = c.AI.DefaultProvider
 	}
@@ -40,4 +42,11 @@ func (c *Config) OverrideFromFlags(
 		providerConfig.Temperature = &newTemp
 	}
 	c.AI.Providers[targetProvider] = providerConfig
+
+	if *candidates != -1 {
+		c.AI.Candidates = *candidates
+	}
+	if *noCache {
+		c.AI.Cache.Enabled = false
+	}
 }
@@ -17,6 +17,8 @@ func setupTestFlags(t *testing.T, args []string) (
 	model *string,
 	maxTokens *int,
 	commitType *string,
+	candidates *int,
+	noCache *bool,
 ) {
 	oldArgs := os.Args
 	t.Cleanup(func() { os.Args = oldArgs })
@@ -29,15 +31,17 @@ func setupTestFlags(t *testing.T, args []string) (
 	model = flag.String("model", "", "AI model to use")
 	maxTokens = flag.Int("max-tokens", -1, "Maximum number of tokens for the AI model")
 	commitType = flag.String("commit-type", "", "Type of commit (e.g., feat, fix, test)")
+	candidates = flag.Int("candidates", -1, "Number of alternative commit messages to generate")
+	noCache = flag.Bool("no-cache", false, "Skip the response cache")
 	flag.Parse()
 	return
 }
 
 func TestOverrideFromFlags_ForcedCommitType(t *testing.T) {
-	provider, apiKey, temperature, model, maxTokens, commitType := setupTestFlags(t, []string{"-commit-type", "feat"})
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{"-commit-type", "feat"})
 
 	cfg := NewDefaultConfig()
-	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
 
 	if cfg.ForcedCommitType != "feat" {
 		t.Errorf("expected ForcedCommitType 'feat', got %q", cfg.ForcedCommitType)
@@ -45,7 +49,7 @@ func TestOverrideFromFlags_ForcedCommitType(t *testing.T) {
 }
 
 func TestOverrideFromFlags_AIProviderSettings(t *testing.T) {
-	provider, apiKey, temperature, model, maxTokens, commitType := setupTestFlags(t, []string{
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{
 		"-api-key", "test-key",
 		"-model", "test-model",
 		"-max-tokens", "500",
@@ -53,7 +57,7 @@ func TestOverrideFromFlags_AIProviderSettings(t *testing.T) {
 	})
 
 	cfg := NewDefaultConfig()
-	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
 
 	providerCfg := cfg.AI.Providers[cfg.AI.DefaultProvider]
 	if providerCfg.APIKey != "test-key" {
@@ -71,7 +75,7 @@ func TestOverrideFromFlags_AIProviderSettings(t *testing.T) {
 }
 
 func TestOverrideFromFlags_SpecificProviderSettings(t *testing.T) {
-	provider, apiKey, temperature, model, maxTokens, commitType := setupTestFlags(t, []string{
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{
 		"-provider", "gemini",
 		"-api-key", "gemini-key",
 		"-model", "gemini-model",
@@ -82,7 +86,7 @@ func TestOverrideFromFlags_SpecificProviderSettings(t *testing.T) {
 	if _, ok := cfg.AI.Providers[Gemini]; !ok {
 		t.Fatalf("Gemini provider not found in default config, cannot test specific override.")
 	}
-	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
 
 	geminiCfg := cfg.AI.Providers[Gemini]
 	if geminiCfg.APIKey != "gemini-key" {
@@ -94,11 +98,11 @@ func TestOverrideFromFlags_SpecificProviderSettings(t *testing.T) {
 }
 
 func TestOverrideFromFlags_NoFlags(t *testing.T) {
-	provider, apiKey, temperature, model, maxTokens, commitType := setupTestFlags(t, []string{})
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{})
 
 	initialCfg := NewDefaultConfig()
 	cfg := NewDefaultConfig() // Create a separate config to modify
-	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
 
 	/*
 		Deep compare initialCfg and cfg to ensure no changes
@@ -119,11 +123,11 @@ func TestOverrideFromFlags_NoFlags(t *testing.T) {
 }
 
 func TestOverrideFromFlags_PartialFlags(t *testing.T) {
-	provider, apiKey, temperature, model, maxTokens, commitType := setupTestFlags(t, []string{"-api-key", "partial-key"})
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{"-api-key", "partial-key"})
 
 	cfg := NewDefaultConfig()
 	originalModel := cfg.AI.Providers[cfg.AI.DefaultProvider].Model // Store original model
-	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens)
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
 
 	providerCfg := cfg.AI.Providers[cfg.AI.DefaultProvider]
 
@@ -135,3 +139,37 @@ func TestOverrideFromFlags_PartialFlags(t *testing.T) {
 		t.Errorf("expected Model to remain %q, got %q", originalModel, providerCfg.Model)
 	}
 }
+
+func TestOverrideFromFlags_ProviderSelectsDefault(t *testing.T) {
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{"-provider", "openai"})
+
+	cfg := NewDefaultConfig()
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
+
+	if cfg.AI.DefaultProvider != OpenAI {
+		t.Errorf("expected DefaultProvider %q, got %q", OpenAI, cfg.AI.DefaultProvider)
+	}
+}
+
+func TestOverrideFromFlags_Candidates(t *testing.T) {
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{"-candidates", "3"})
+
+	cfg := NewDefaultConfig()
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
+
+	if cfg.AI.Candidates != 3 {
+		t.Errorf("expected Candidates 3, got %d", cfg.AI.Candidates)
+	}
+}
+
+func TestOverrideFromFlags_NoCache(t *testing.T) {
+	provider, apiKey, temperature, model, maxTokens, commitType, candidates, noCache := setupTestFlags(t, []string{"-no-cache"})
+
+	cfg := NewDefaultConfig()
+	cfg.AI.Cache.Enabled = true
+	cfg.OverrideFromFlags(commitType, provider, apiKey, model, temperature, maxTokens, candidates, noCache)
+
+	if cfg.AI.Cache.Enabled {
+		t.Error("expected -no-cache to disable the response cache")
+	}
+}
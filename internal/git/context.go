@@ -0,0 +1,128 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+/*
+RepoContext describes the repository a commit is made in. It helps the model match the
+repository's conventions and understand the scope of the staged changes.
+*/
+type RepoContext struct {
+	// RepoName is the name of the repository's top-level directory.
+	RepoName string
+
+	// Branch is the current branch, empty on a detached HEAD, and Upstream its remote-tracking branch, if any.
+	Branch   string
+	Upstream string
+
+	// RecentCommits holds the subjects of the latest commits, newest first.
+	RecentCommits []string
+
+	// StagedFiles lists the staged files along with how they were changed.
+	StagedFiles []StagedFile
+}
+
+/*
+StagedFile is a staged file as reported by 'git diff --staged --name-status'. Status is one of
+A (added), M (modified), D (deleted), R (renamed), C (copied) or T (type changed), and OldPath
+is the original path of a renamed or copied file.
+*/
+type StagedFile struct {
+	Status  string
+	Path    string
+	OldPath string
+}
+
+// String returns the status and path of the file, showing both paths for renames and copies.
+func (f StagedFile) String() string {
+	if f.OldPath != "" {
+		return fmt.Sprintf("%s %s -> %s", f.Status, f.OldPath, f.Path)
+	}
+	return fmt.Sprintf("%s %s", f.Status, f.Path)
+}
+
+/*
+GetRepoContext collects the context of the repository in the current directory, including the
+subjects of up to recentCommits latest commits. Only failing to find the repository is an error:
+a missing upstream or a repository without commits leave the corresponding fields empty.
+*/
+func GetRepoContext(recentCommits int) (RepoContext, error) {
+	root, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return RepoContext{}, err
+	}
+
+	repo := RepoContext{RepoName: filepath.Base(root)}
+	repo.Branch, _ = runGit("branch", "--show-current")
+	repo.Upstream, _ = runGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+
+	if recentCommits > 0 {
+		if log, err := runGit("log", fmt.Sprintf("-%d", recentCommits), "--format=%s"); err == nil && log != "" {
+			repo.RecentCommits = strings.Split(log, "\n")
+		}
+	}
+
+	nameStatus, err := runGit("diff", "--staged", "--name-status", "--find-renames")
+	if err != nil {
+		return RepoContext{}, err
+	}
+	repo.StagedFiles = parseNameStatus(nameStatus)
+	return repo, nil
+}
+
+/*
+GetCommitMessages returns the full messages of up to limit latest non-merge commits, newest
+first. A repository without commits has no messages.
+*/
+func GetCommitMessages(limit int) ([]string, error) {
+	if _, err := runGit("rev-parse", "--show-toplevel"); err != nil {
+		return nil, err
+	}
+
+	// Messages are separated by NUL bytes, since they span several lines themselves.
+	log, err := runGit("log", "--no-merges", fmt.Sprintf("-%d", limit), "--format=%B%x00")
+	if err != nil {
+		// git log fails in a repository without commits.
+		return nil, nil
+	}
+
+	var messages []string
+	for message := range strings.SplitSeq(log, "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// parseNameStatus parses the tab-separated output of 'git diff --name-status'.
+func parseNameStatus(output string) []StagedFile {
+	var files []StagedFile
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		// Renames and copies carry a similarity score, e.g. 'R087', followed by both paths.
+		file := StagedFile{Status: fields[0][:1], Path: fields[len(fields)-1]}
+		if len(fields) == 3 {
+			file.OldPath = fields[1]
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// runGit runs git with the given arguments and returns its trimmed standard output.
+func runGit(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
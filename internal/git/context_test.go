@@ -0,0 +1,122 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGetRepoContext covers the repository name, branch, recent commits and staged files.
+func TestGetRepoContext(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	t.Chdir(repoPath)
+
+	runCmd := func(args ...string) {
+		t.Helper()
+		output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("command %q failed: %v\nOutput: %s", strings.Join(args, " "), err, string(output))
+		}
+	}
+
+	t.Run("without commits", func(t *testing.T) {
+		repo, err := GetRepoContext(10)
+		if err != nil {
+			t.Fatalf("GetRepoContext() failed: %v", err)
+		}
+		if repo.RepoName != filepath.Base(repoPath) {
+			t.Errorf("expected repo name %q, got %q", filepath.Base(repoPath), repo.RepoName)
+		}
+		if len(repo.RecentCommits) != 0 || len(repo.StagedFiles) != 0 {
+			t.Errorf("expected no commits and no staged files, got %+v", repo)
+		}
+	})
+
+	os.WriteFile("old.txt", []byte("one\ntwo\nthree\nfour\n"), 0644)
+	os.WriteFile("edit.txt", []byte("before\n"), 0644)
+	runCmd("git", "add", ".")
+	runCmd("git", "commit", "-m", "feat: first commit")
+	runCmd("git", "commit", "--allow-empty", "-m", "fix: second commit")
+	runCmd("git", "checkout", "-b", "feature/context")
+
+	runCmd("git", "mv", "old.txt", "new.txt")
+	os.WriteFile("edit.txt", []byte("after\n"), 0644)
+	os.WriteFile("added.txt", []byte("added\n"), 0644)
+	runCmd("git", "add", ".")
+
+	t.Run("with commits and staged changes", func(t *testing.T) {
+		repo, err := GetRepoContext(1)
+		if err != nil {
+			t.Fatalf("GetRepoContext() failed: %v", err)
+		}
+		if repo.Branch != "feature/context" {
+			t.Errorf("expected branch %q, got %q", "feature/context", repo.Branch)
+		}
+		if repo.Upstream != "" {
+			t.Errorf("expected no upstream, got %q", repo.Upstream)
+		}
+		if !reflect.DeepEqual(repo.RecentCommits, []string{"fix: second commit"}) {
+			t.Errorf("expected only the latest commit, got %q", repo.RecentCommits)
+		}
+
+		expected := []StagedFile{
+			{Status: "A", Path: "added.txt"},
+			{Status: "M", Path: "edit.txt"},
+			{Status: "R", Path: "new.txt", OldPath: "old.txt"},
+		}
+		if !reflect.DeepEqual(repo.StagedFiles, expected) {
+			t.Errorf("expected staged files %+v, got %+v", expected, repo.StagedFiles)
+		}
+	})
+
+	t.Run("not in a git repository", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		if _, err := GetRepoContext(10); err == nil {
+			t.Fatal("expected an error when running outside a git repository, but got nil")
+		}
+	})
+}
+
+func TestStagedFile_String(t *testing.T) {
+	testCases := []struct {
+		file     StagedFile
+		expected string
+	}{
+		{StagedFile{Status: "M", Path: "main.go"}, "M main.go"},
+		{StagedFile{Status: "R", Path: "new.go", OldPath: "old.go"}, "R old.go -> new.go"},
+	}
+	for _, tc := range testCases {
+		if got := tc.file.String(); got != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestGetCommitMessages(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	t.Chdir(repoPath)
+
+	messages, err := GetCommitMessages(10)
+	if err != nil || len(messages) != 0 {
+		t.Fatalf("expected no messages without commits, got %q, %v", messages, err)
+	}
+
+	for _, message := range []string{"feat: first\n\n- Add a body", "fix: second"} {
+		cmd := exec.Command("git", "commit", "--allow-empty", "-m", message)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %v\nOutput: %s", err, output)
+		}
+	}
+
+	messages, err = GetCommitMessages(10)
+	if err != nil {
+		t.Fatalf("GetCommitMessages() failed: %v", err)
+	}
+	expected := []string{"fix: second", "feat: first\n\n- Add a body"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected messages %q, got %q", expected, messages)
+	}
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FileDiff is the part of a unified diff that describes the changes to a single file.
+type FileDiff struct {
+	Path      string
+	Header    string
+	Hunks     []Hunk
+	Additions int
+	Deletions int
+}
+
+// Hunk is a single '@@' section of a file diff.
+type Hunk struct {
+	Header string
+	Body   string
+}
+
+/*
+ParseDiff splits the output of 'git diff' into one FileDiff per file. Lines that appear before
+the first 'diff --git' line are ignored.
+*/
+func ParseDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var current *FileDiff
+	var hunk *Hunk
+	var header, body strings.Builder
+
+	flushHunk := func() {
+		if hunk != nil {
+			hunk.Body = body.String()
+			current.Hunks = append(current.Hunks, *hunk)
+			hunk = nil
+			body.Reset()
+		}
+	}
+	flushFile := func() {
+		if current != nil {
+			flushHunk()
+			current.Header = header.String()
+			files = append(files, *current)
+			header.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushFile()
+			current = &FileDiff{Path: diffPath(line)}
+			header.WriteString(line)
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk = &Hunk{Header: line}
+		case hunk == nil:
+			header.WriteString(line)
+		default:
+			body.WriteString(line)
+			if strings.HasPrefix(line, "+") {
+				current.Additions++
+			} else if strings.HasPrefix(line, "-") {
+				current.Deletions++
+			}
+		}
+	}
+	flushFile()
+	return files
+}
+
+// diffPath extracts the new path of a file from a 'diff --git a/<old> b/<new>' line.
+func diffPath(line string) string {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "diff --git "))
+	if i := strings.LastIndex(line, " b/"); i != -1 {
+		return line[i+len(" b/"):]
+	}
+	return line
+}
+
+// String returns the file diff in its original unified diff form.
+func (f FileDiff) String() string {
+	var diff strings.Builder
+	diff.WriteString(f.Header)
+	for _, hunk := range f.Hunks {
+		diff.WriteString(hunk.Header)
+		diff.WriteString(hunk.Body)
+	}
+	return diff.String()
+}
+
+// HunkHeaders returns the file diff with the content of every hunk left out, keeping only the '@@' lines.
+func (f FileDiff) HunkHeaders() string {
+	var diff strings.Builder
+	diff.WriteString(f.Header)
+	for _, hunk := range f.Hunks {
+		diff.WriteString(hunk.Header)
+	}
+	return diff.String()
+}
+
+/*
+MatchesAny reports whether the file's path or name matches one of the given glob patterns.
+A pattern ending in '/' matches every file inside a directory of that name.
+*/
+func (f FileDiff) MatchesAny(patterns []string) bool {
+	for _, pattern := range patterns {
+		if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+			if strings.HasPrefix(f.Path, dir+"/") || strings.Contains(f.Path, "/"+dir+"/") {
+				return true
+			}
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, f.Path); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(f.Path)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+DiffStat returns a summary of the given files in the spirit of 'git diff --stat': one line per
+file with its number of changed lines, followed by the totals.
+*/
+func DiffStat(files []FileDiff) string {
+	width := 0
+	for _, file := range files {
+		width = max(width, len(file.Path))
+	}
+
+	var stat strings.Builder
+	var additions, deletions int
+	for _, file := range files {
+		fmt.Fprintf(&stat, " %-*s | %d (+%d -%d)\n", width, file.Path, file.Additions+file.Deletions, file.Additions, file.Deletions)
+		additions += file.Additions
+		deletions += file.Deletions
+	}
+	fmt.Fprintf(&stat, " %d files changed, %d insertions(+), %d deletions(-)\n", len(files), additions, deletions)
+	return stat.String()
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@ package main
+ import "fmt"
++import "os"
+-func old() {}
++func new() {}
+@@ -10,2 +11,2 @@ func main() {
+-	fmt.Println("hi")
++	fmt.Println("hello")
+diff --git a/web/package-lock.json b/web/package-lock.json
+index 3333333..4444444 100644
+--- a/web/package-lock.json
++++ b/web/package-lock.json
+@@ -1 +1 @@
+-{"lockfileVersion": 2}
++{"lockfileVersion": 3}
+`
+
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff(testDiff)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+
+	main := files[0]
+	if main.Path != "main.go" {
+		t.Errorf("expected path 'main.go', got %q", main.Path)
+	}
+	if len(main.Hunks) != 2 {
+		t.Errorf("expected 2 hunks, got %d", len(main.Hunks))
+	}
+	if main.Additions != 3 || main.Deletions != 2 {
+		t.Errorf("expected +3 -2, got +%d -%d", main.Additions, main.Deletions)
+	}
+
+	// Re-assembling the parsed files must give back the original diff.
+	var rebuilt strings.Builder
+	for _, file := range files {
+		rebuilt.WriteString(file.String())
+	}
+	if rebuilt.String() != testDiff {
+		t.Errorf("expected the parsed diff to round-trip, got:\n%s", rebuilt.String())
+	}
+}
+
+func TestFileDiff_HunkHeaders(t *testing.T) {
+	headers := ParseDiff(testDiff)[0].HunkHeaders()
+	if strings.Contains(headers, "fmt.Println") {
+		t.Errorf("expected hunk content to be left out, got:\n%s", headers)
+	}
+	if strings.Count(headers, "@@ -") != 2 || !strings.Contains(headers, "+++ b/main.go") {
+		t.Errorf("expected the file header and both hunk headers, got:\n%s", headers)
+	}
+}
+
+func TestFileDiff_MatchesAny(t *testing.T) {
+	testCases := []struct {
+		path     string
+		patterns []string
+		expected bool
+	}{
+		{path: "web/package-lock.json", patterns: []string{"package-lock.json"}, expected: true},
+		{path: "Cargo.lock", patterns: []string{"*.lock"}, expected: true},
+		{path: "api/v1/service.pb.go", patterns: []string{"*.pb.go"}, expected: true},
+		{path: "vendor/github.com/x/y.go", patterns: []string{"vendor/"}, expected: true},
+		{path: "tools/vendor/a.go", patterns: []string{"vendor/"}, expected: true},
+		{path: "internal/vendors.go", patterns: []string{"vendor/"}, expected: false},
+		{path: "main.go", patterns: []string{"*.lock", "dist/"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			file := FileDiff{Path: tc.path}
+			if got := file.MatchesAny(tc.patterns); got != tc.expected {
+				t.Errorf("expected %v for patterns %q, got %v", tc.expected, tc.patterns, got)
+			}
+		})
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	stat := DiffStat(ParseDiff(testDiff))
+
+	expected := " main.go               | 5 (+3 -2)\n" +
+		" web/package-lock.json | 2 (+1 -1)\n" +
+		" 2 files changed, 4 insertions(+), 3 deletions(-)\n"
+	if stat != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stat)
+	}
+}
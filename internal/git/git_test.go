@@ -181,9 +181,9 @@ func TestFindGitRoot(t *testing.T) {
 		os.MkdirAll(subDir, 0755)
 		os.Chdir(subDir)
 
-		foundRoot, err := findGitRoot()
+		foundRoot, err := FindGitRoot()
 		if err != nil {
-			t.Fatalf("findGitRoot() failed: %v", err)
+			t.Fatalf("FindGitRoot() failed: %v", err)
 		}
 
 		// Clean paths for reliable comparison
@@ -199,7 +199,7 @@ func TestFindGitRoot(t *testing.T) {
 		nonRepoPath := t.TempDir()
 		os.Chdir(nonRepoPath)
 
-		_, err := findGitRoot()
+		_, err := FindGitRoot()
 		if err == nil {
 			t.Fatal("expected an error when running outside a git repository, but got nil")
 		}
@@ -45,7 +45,7 @@ Install creates or overwrites the prepare-commit-msg Git hook in the current
 repository. It returns an error if the hook file cannot be created or written to.
 */
 func Install() error {
-	repoRoot, err := findGitRoot()
+	repoRoot, err := FindGitRoot()
 	if err != nil {
 		return fmt.Errorf("could not find Git repository root: %w", err)
 	}
@@ -64,7 +64,7 @@ Uninstall removes the prepare-commit-msg Git hook from the current repository.
 It returns a nil error if the file does not exist.
 */
 func Uninstall() error {
-	repoRoot, err := findGitRoot()
+	repoRoot, err := FindGitRoot()
 	if err != nil {
 		return fmt.Errorf("could not uninstall hook: %w", err)
 	}
@@ -81,8 +81,8 @@ func Uninstall() error {
 	return nil
 }
 
-// findGitRoot traverses up the directory tree to find the root of the Git repository.
-func findGitRoot() (string, error) {
+// FindGitRoot traverses up the directory tree to find the root of the Git repository.
+func FindGitRoot() (string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return "", fmt.Errorf("could not get current working directory: %w", err)